@@ -2,33 +2,173 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/anupcshan/sqlgen/sqlgen"
 )
 
 var (
-	typeNames = flag.String("type", "", "comma-separated list of type names [required]")
+	typeNames    = flag.String("type", "", "comma-separated list of type names; default the types marked with a //sqlgen:table comment")
+	templatesDir = flag.String("templates", "", "directory of *.tmpl files overriding or adding to the built-in templates")
+	pluginNames  = flag.String("plugins", "", "comma-separated list of plugins generating additional files; registered plugins, or else executables")
+	strict       = flag.Bool("strict", os.Getenv("CI") != "", "fail on fields which are skipped, unless tagged sqlgen:\"-\"; default on if $CI is set")
+	outputDir    = flag.String("output", "", "directory of the generated files; default the directory of the package declaring each type")
+	packageName  = flag.String("package", "", "package of the generated files, if -output is not the directory of the package declaring a type; default the base name of -output")
 )
 
+// Usage is a replacement usage function for the flags package.
+func Usage() {
+	fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\tneosqlgen [flags] [-type T[,T...]] [directories or package patterns]\n")
+	fmt.Fprintf(os.Stderr, "Types may be qualified by the import path of their package, as in example.com/models.T.\n")
+	fmt.Fprintf(os.Stderr, "Without -type, queries are generated for the types whose declaration is marked with a\n")
+	fmt.Fprintf(os.Stderr, "%s comment, each in a file of its own.\n", sqlgen.TableDirective)
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+	flag.PrintDefaults()
+}
+
 func main() {
+	log.SetFlags(0)
+	log.SetPrefix("neosqlgen: ")
+	flag.Usage = Usage
 	flag.Parse()
 
-	if len(*typeNames) == 0 {
-		flag.Usage()
-		os.Exit(1)
-	}
-
 	args := flag.Args()
 	if len(args) == 0 {
 		args = []string{"."}
 	}
 
+	templates := sqlgen.DefaultTemplates()
+	if *templatesDir != "" {
+		var err error
+		if templates, err = sqlgen.LoadTemplates(*templatesDir); err != nil {
+			log.Fatalf("Error loading templates: %s", err)
+		}
+	}
+
+	var plugins []sqlgen.Plugin
+	if *pluginNames != "" {
+		for _, name := range strings.Split(*pluginNames, ",") {
+			if p, ok := sqlgen.LookupPlugin(name); ok {
+				plugins = append(plugins, p)
+			} else {
+				plugins = append(plugins, sqlgen.NewExecPlugin(name))
+			}
+		}
+	}
+
 	parser := sqlgen.NewParser()
+	parser.SetStrict(*strict)
 
-	for _, dir := range args {
-		parser.AddDirectory(dir)
+	for _, arg := range args {
+		if info, err := os.Stat(arg); err == nil && info.IsDir() {
+			if err := parser.AddDirectory(arg); err != nil {
+				log.Fatalf("Error adding directory: %s", err)
+			}
+		} else {
+			parser.AddPattern(arg)
+		}
 	}
 
-	parser.ParseFiles()
+	if err := parser.ParseFiles(); err != nil {
+		log.Fatalf("Error parsing files: %s", err)
+	}
+
+	var names []string
+	if *typeNames != "" {
+		names = strings.Split(*typeNames, ",")
+	} else if names = parser.TableTypes(); len(names) == 0 {
+		log.Fatalf("Error: no type marked with %s in %s", sqlgen.TableDirective, strings.Join(args, " "))
+	}
+
+	// Types are generated into the directory of their package, unless another
+	// output is set.
+	var dirs []string
+	typesByDir := make(map[string][]*sqlgen.Type)
+	for _, typeName := range names {
+		t, err := parser.ParseType(typeName)
+		if err != nil {
+			log.Fatalf("Error parsing type: %s", err)
+		}
+
+		dir := t.Dir
+		if *outputDir != "" {
+			if dir, err = outputPackage(t, *outputDir, *packageName); err != nil {
+				log.Fatalf("Error setting output package: %s", err)
+			}
+		}
+		if _, ok := typesByDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		typesByDir[dir] = append(typesByDir[dir], t)
+
+		g := sqlgen.NewGenerator(t)
+		g.SetTemplates(templates)
+		if err := g.Generate(); err != nil {
+			log.Fatalf("Error generating %s: %s", typeName, err)
+		}
+
+		outputName := filepath.Join(dir, strings.ToLower(fmt.Sprintf("%s_query.go", t.Name)))
+		if err := ioutil.WriteFile(outputName, g.Bytes(), 0644); err != nil {
+			log.Fatalf("Error writing output: %s", err)
+		}
+	}
+
+	// Transactions spanning several types of a package go through a Store.
+	for _, dir := range dirs {
+		types := typesByDir[dir]
+		if len(types) < 2 {
+			continue
+		}
+
+		g := sqlgen.NewStoreGenerator(types)
+		g.SetTemplates(templates)
+		if err := g.Generate(); err != nil {
+			log.Fatalf("Error generating store: %s", err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(dir, "store.go"), g.Bytes(), 0644); err != nil {
+			log.Fatalf("Error writing output: %s", err)
+		}
+	}
+
+	// Plugins see all types, and write into the first output directory.
+	if len(plugins) > 0 {
+		var model []sqlgen.Type
+		for _, dir := range dirs {
+			for _, t := range typesByDir[dir] {
+				model = append(model, *t)
+			}
+		}
+		if err := sqlgen.RunPlugins(plugins, model, dirs[0]); err != nil {
+			log.Fatalf("Error running plugins: %s", err)
+		}
+	}
+}
+
+// outputPackage makes the code of t go into the directory output, creating it
+// if needed, and returns its absolute path. Unless output is the directory of
+// the package declaring t, the code goes into the package name, or one named
+// after the directory if empty.
+func outputPackage(t *sqlgen.Type, output, name string) (string, error) {
+	dir, err := filepath.Abs(output)
+	if err != nil {
+		return "", err
+	}
+	if dir == t.Dir {
+		return dir, nil
+	}
+
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+	if err := t.SetPackage(name); err != nil {
+		return "", err
+	}
+	return dir, os.MkdirAll(dir, 0755)
 }
@@ -10,7 +10,7 @@ func CreateNewFoo(f *model.FooQuery) {
 		cf, ce := tx.ByBar("bar")
 		select {
 		case foo := <-cf:
-			fmt.Printf("Found foo: %s\n", foo)
+			fmt.Printf("Found foo: %+v\n", foo)
 		case err := <-ce:
 			fmt.Printf("Found error: %s\n", err)
 			break
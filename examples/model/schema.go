@@ -6,7 +6,9 @@ type Interface interface {
 	IMethod()
 }
 
-//go:generate sqlgen -type=Foo
+//go:generate sqlgen
+
+//sqlgen:table
 type Foo struct {
 	// Primary key: id
 	Id int64
@@ -21,13 +23,13 @@ type Foo struct {
 	Created time.Time
 
 	// FK: type2
-	Type2Ptr *Type2
+	Type2Ptr *Type2 `sqlgen:"-"`
 
 	// Not supported: FKL type3
-	Type3Obj Type3
+	Type3Obj Type3 `sqlgen:"-"`
 
 	// One-to-many type4
-	Type4List []*Type4
+	Type4List []*Type4 `sqlgen:"-"`
 }
 
 type Type2 struct {
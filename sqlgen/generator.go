@@ -4,337 +4,167 @@ import (
 	"bytes"
 	"fmt"
 	"go/format"
+	"strings"
 )
 
-// Value represents a declared field.
-type Field struct {
-	srcName string // Field name in source
-	dbName  string // Field name in DB
-	isPK    bool   // Is the field a primary key?
-	srcType string // Field type in source
-	dbType  string // Expected field type in the DB
+// needsClock reports whether generated methods read the current time.
+func (t *Type) needsClock() bool {
+	return t.Created() != nil || t.Updated() != nil || t.SoftDelete() != nil
 }
 
-// Each struct type. Maps to one table in the database.
-type Type struct {
-	name        string  // Type name in source
-	tableName   string  // Table name in DB
-	fields      []Field // List of fields synced with DB
-	packageName string  // Package that new type should go into
-	// TODO: Do we need a mechanism to refer to other tables?
-}
-
-type SourceWriter struct {
-	buf         bytes.Buffer
-	indentLevel int
-}
-
-func (s *SourceWriter) Indent() *SourceWriter {
-	s.indentLevel++
-	return s
-}
-
-func (s *SourceWriter) Unindent() *SourceWriter {
-	if s.indentLevel == 0 {
-		panic("Cannot unindent from left edge.")
+// finderIndexes returns the primary key followed by the declared indexes.
+func (t *Type) finderIndexes() []Index {
+	pk := t.PK()
+	indexes := []Index{{Name: pk.Column, Fields: []Field{pk}, Unique: true}}
+	seen := map[string]bool{indexes[0].FinderName(): true}
+	for _, idx := range t.Indexes {
+		if !seen[idx.FinderName()] {
+			seen[idx.FinderName()] = true
+			indexes = append(indexes, idx)
+		}
 	}
-	s.indentLevel--
-	return s
-}
-
-type CompoundStatement struct {
-	sw *SourceWriter
+	return indexes
 }
 
-func (s *SourceWriter) newCompoundStatement(initialIndent bool, format string, args ...interface{}) *CompoundStatement {
-	if initialIndent {
-		s.Printf(format, args...)
-	} else {
-		s.printf(format, args...)
+// uniqueIndexes returns the indexes which are looked up with a prepared statement.
+func (t *Type) uniqueIndexes() []Index {
+	var indexes []Index
+	for _, idx := range t.finderIndexes() {
+		if idx.Unique {
+			indexes = append(indexes, idx)
+		}
+	}
+	return indexes
+}
+
+// updateStatement returns the UPDATE statement of the type, and the fields
+// bound to its placeholders, in order. Soft deleted rows are left unchanged.
+func (t *Type) updateStatement() (string, []Field) {
+	var args []Field
+	var assignments []string
+	for _, field := range t.Fields {
+		switch {
+		case field.IsPK, field.IsCreated, field.IsSoftDelete:
+			// Never changed by an update: soft deletes go through Delete.
+		case field.IsVersion:
+			// The version is bumped by every update, and the current version
+			// must match.
+			assignments = append(assignments, fmt.Sprintf("%s=%s+1", field.Column, field.Column))
+		default:
+			args = append(args, field)
+			assignments = append(assignments, fmt.Sprintf("%s=$%d", field.Column, len(args)))
+		}
 	}
-	s.buf.Write([]byte(" {\n"))
-	s.Indent()
-
-	return &CompoundStatement{sw: s}
-}
-
-func (s *SourceWriter) NewCompoundStatement(format string, args ...interface{}) *CompoundStatement {
-	return s.newCompoundStatement(true, format, args...)
-}
-
-func (s *SourceWriter) ContinueCompoundStatement(format string, args ...interface{}) *CompoundStatement {
-	return s.newCompoundStatement(false, format, args...)
-}
 
-func (cs *CompoundStatement) Printfln(format string, args ...interface{}) *CompoundStatement {
-	cs.sw.Printfln(format, args...)
-	return cs
-}
+	pk := t.PK()
+	args = append(args, pk)
+	conditions := fmt.Sprintf("%s=$%d", pk.Column, len(args))
+	if version := t.Version(); version != nil {
+		args = append(args, *version)
+		conditions += fmt.Sprintf(" AND %s=$%d", version.Column, len(args))
+	}
+	if deleted := t.SoftDelete(); deleted != nil {
+		// Deleted rows are not updated, nor brought back.
+		conditions += fmt.Sprintf(" AND %s IS NULL", deleted.Column)
+	}
 
-func (cs *CompoundStatement) NewCompoundStatement(format string, args ...interface{}) *CompoundStatement {
-	return cs.sw.NewCompoundStatement(format, args...)
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s", t.Table, strings.Join(assignments, ","), conditions), args
 }
 
-func (cs *CompoundStatement) AddNewline() *CompoundStatement {
-	cs.sw.AddNewline()
-	return cs
+// updateArgs returns the fields bound to the placeholders of the UPDATE
+// statement, in order.
+func (t *Type) updateArgs() []Field {
+	_, args := t.updateStatement()
+	return args
 }
 
-func (cs *CompoundStatement) CloseAndReopen(format string, args ...interface{}) *CompoundStatement {
-	cs.sw.Unindent()
-	cs.sw.Printf("} ")
-	return cs.sw.ContinueCompoundStatement(format, args...)
+// statement is a prepared statement of the query type, held in the field Name.
+type statement struct {
+	Name  string
+	Query string
 }
 
-func (cs *CompoundStatement) Close() *SourceWriter {
-	cs.sw.Unindent()
-	cs.sw.Printfln("}")
-	return cs.sw
-}
+// statements returns the prepared statements of the type, in the order of
+// their fields. Their placeholders are numbered in order of appearance, to be
+// rebound for the dialect at run time.
+func (t *Type) statements() []statement {
+	dbFieldNames := columns(t.Fields)
+	pk := t.PK()
 
-func (s *SourceWriter) PrintIndentation() *SourceWriter {
-	for i := 0; i < s.indentLevel; i++ {
-		s.buf.WriteByte('\t')
+	var stmts []statement
+	addStmt := func(name, format string, args ...interface{}) {
+		stmts = append(stmts, statement{Name: name, Query: fmt.Sprintf(format, args...)})
 	}
-	return s
-}
 
-func (s *SourceWriter) printf(format string, args ...interface{}) *SourceWriter {
-	fmt.Fprintf(&s.buf, format, args...)
-	return s
-}
+	addStmt("create", "INSERT INTO %s(%s) VALUES(%s)", t.Table, dbFieldNames, placeholders(t.Fields))
 
-func (s *SourceWriter) Printf(format string, args ...interface{}) *SourceWriter {
-	return s.PrintIndentation().printf(format, args...)
-}
+	for _, idx := range t.uniqueIndexes() {
+		var conditions bytes.Buffer
+		for i, field := range idx.Fields {
+			if i != 0 {
+				conditions.WriteString(" AND ")
+			}
+			conditions.WriteString(fmt.Sprintf("%s=$%d", field.Column, i+1))
+		}
+		if deleted := t.SoftDelete(); deleted != nil {
+			conditions.WriteString(fmt.Sprintf(" AND %s IS NULL", deleted.Column))
+		}
+		addStmt("by"+idx.FinderName(), "SELECT %s FROM %s WHERE %s", dbFieldNames, t.Table, conditions.String())
+	}
 
-func (s *SourceWriter) Printfln(format string, args ...interface{}) *SourceWriter {
-	return s.Printf(format, args...).AddNewline()
-}
+	update, _ := t.updateStatement()
+	addStmt("update", "%s", update)
 
-func (s *SourceWriter) AddNewline() *SourceWriter {
-	s.buf.WriteByte('\n')
-	return s
+	deleteStmt := "delete"
+	if deleted := t.SoftDelete(); deleted != nil {
+		addStmt("delete", "UPDATE %s SET %s=$1 WHERE %s=$2 AND %s IS NULL", t.Table, deleted.Column, pk.Column, deleted.Column)
+		deleteStmt = "hardDelete"
+	}
+	addStmt(deleteStmt, "DELETE FROM %s WHERE %s=$1", t.Table, pk.Column)
+	return stmts
 }
 
-func (s *SourceWriter) Format() error {
-	formattedBytes, err := format.Source(s.buf.Bytes())
+// formatSource replaces the source in buf with its gofmt-ed form.
+func formatSource(buf *bytes.Buffer) error {
+	formattedBytes, err := format.Source(buf.Bytes())
 	if err != nil {
 		return err
 	}
 
-	s.buf.Reset()
-	s.buf.Write(formattedBytes)
+	buf.Reset()
+	buf.Write(formattedBytes)
 	return nil
 }
 
 type Generator struct {
-	sw                *SourceWriter // Output buffer
-	additionalImports []string      // List of additional imports (for local data types)
-	_type             Type          // Struct/table to be exported.
+	buf       bytes.Buffer // Output buffer
+	templates *Templates   // Templates emitting the code; the defaults if nil
+	_type     Type         // Struct/table to be exported.
 }
 
-func (g *Generator) printFileHeader() {
-	g.sw.Printfln("// generated by sqlgen; DO NOT EDIT").AddNewline()
-	g.sw.Printfln("package %s", g._type.packageName)
-	g.sw.AddNewline()
-	g.sw.Printfln(`import "database/sql"`)
-	for _, impt := range g.additionalImports {
-		g.sw.Printfln(`import "%s"`, impt)
-	}
+func NewGenerator(t *Type) *Generator {
+	return &Generator{_type: *t}
 }
 
-func (g *Generator) printQueryDeclaration() {
-	// -- Query definition BEGIN
-	{
-		cs := g.sw.NewCompoundStatement("type %sQuery struct", g._type.name)
-		cs.
-			Printfln("db *sql.DB").
-			Printfln("create *sql.Stmt")
-
-		for _, field := range g._type.fields {
-			cs.Printfln("by%s *sql.Stmt", field.srcName)
-		}
-		cs.Printfln("delete *sql.Stmt")
-		cs.Printfln("update *sql.Stmt")
-		cs.Close()
-	}
-	// -- Query definition END
-
-	g.sw.AddNewline()
-
-	// -- Query transaction definition BEGIN
-	{
-		queryTransactionClass := fmt.Sprintf("%sQueryTx", g._type.name)
-		cs := g.sw.NewCompoundStatement("type %s struct", queryTransactionClass)
-		cs.
-			Printfln("tx *sql.Tx").
-			Printfln("q *%sQuery", g._type.name).
-			Close()
-	}
-	// -- Query transaction definition END
+// SetTemplates makes g emit code with templates instead of the defaults.
+func (g *Generator) SetTemplates(templates *Templates) {
+	g.templates = templates
 }
 
-func (g *Generator) printSchemaValidation() {
-	var nonPKDbFieldNames bytes.Buffer
-	var nonPKPlaceholders bytes.Buffer
-
-	var pKDbFieldNames bytes.Buffer
-	var pKPlaceholders bytes.Buffer
-
-	var dbFieldNames bytes.Buffer
-	var placeholders bytes.Buffer
-	for i, field := range g._type.fields {
-		if i != 0 {
-			dbFieldNames.WriteString(",")
-			placeholders.WriteString(",")
-		}
-
-		dbFieldNames.WriteString(field.dbName)
-		placeholders.WriteString(fmt.Sprintf("$%d", i+1))
-		if field.isPK {
-			if pKDbFieldNames.Len() != 0 {
-				panic("Multiple primary key columns not implemented.")
-			}
-
-			pKDbFieldNames.WriteString(field.dbName)
-			pKPlaceholders.WriteString(fmt.Sprintf("$%d", i+1))
-		} else {
-			if nonPKDbFieldNames.Len() != 0 {
-				nonPKDbFieldNames.WriteString(",")
-				nonPKPlaceholders.WriteString(",")
-			}
-
-			nonPKDbFieldNames.WriteString(field.dbName)
-			nonPKPlaceholders.WriteString(fmt.Sprintf("$%d", i+1))
-		}
-	}
-
-	method := g.sw.NewCompoundStatement("func (q *%sQuery) Validate() error", g._type.name)
-	method.NewCompoundStatement(`if stmt, err := q.db.Prepare("INSERT INTO %s(%s) VALUES(%s)"); err != nil`,
-		g._type.tableName, dbFieldNames.String(), placeholders.String()).
-		Printfln("return err").
-		CloseAndReopen("else").
-		Printfln("q.create = stmt").
-		Close()
-
-	for _, field := range g._type.fields {
-		// TODO: Ideally, this newline would be added automatically.
-		method.AddNewline()
-
-		method.NewCompoundStatement(`if stmt, err := q.db.Prepare("SELECT %s FROM %s WHERE %s=$1"); err != nil`,
-			dbFieldNames.String(), g._type.tableName, field.dbName).
-			Printfln("return err").
-			CloseAndReopen("else").
-			Printfln("q.by%s = stmt", field.srcName).
-			Close()
-	}
-
-	// TODO: Ideally, this newline would be added automatically.
-	method.AddNewline()
-
-	method.NewCompoundStatement(`if stmt, err := q.db.Prepare("UPDATE %s SET (%s)=(%s) WHERE %s=%s"); err != nil`,
-		g._type.tableName, nonPKDbFieldNames.String(), nonPKPlaceholders.String(), pKDbFieldNames.String(), pKPlaceholders.String()).
-		Printfln("return err").
-		CloseAndReopen("else").
-		Printfln("q.update = stmt").
-		Close()
-
-	method.AddNewline()
-
-	method.NewCompoundStatement(`if stmt, err := q.db.Prepare("DELETE FROM %s WHERE %s=%s"); err != nil`,
-		g._type.tableName, pKDbFieldNames.String(), pKPlaceholders.String()).
-		Printfln("return err").
-		CloseAndReopen("else").
-		Printfln("q.delete = stmt").
-		Close()
-
-	method.AddNewline()
-
-	method.
-		Printfln("return nil").
-		Close()
+// execute appends the output of the template name for the type.
+func (g *Generator) execute(name string) error {
+	return g.templates.execute(&g.buf, name, &g._type)
 }
 
-func (g *Generator) printInstanceCUD() {
-	var srcFieldPtrs bytes.Buffer
-	var pkSrcFieldPtrs bytes.Buffer
-	for i, field := range g._type.fields {
-		if i != 0 {
-			srcFieldPtrs.WriteString(", ")
-		}
-		srcFieldPtrs.WriteString(fmt.Sprintf("&obj.%s", field.srcName))
-
-		if field.isPK {
-			if pkSrcFieldPtrs.Len() != 0 {
-				panic("Multiple primary key columns not implemented.")
-			}
-			pkSrcFieldPtrs.WriteString(fmt.Sprintf("&obj.%s", field.srcName))
-		}
+// Generate emits the queries of the type, from the template "file".
+func (g *Generator) Generate() error {
+	if err := g.execute("file"); err != nil {
+		return err
 	}
-
-	method := g.sw.NewCompoundStatement("func (t *%[1]sQueryTx) Create(obj *%[1]s) error", g._type.name)
-	method.
-		Printfln("stmt := t.tx.Stmt(t.q.create)").
-		NewCompoundStatement("if _, err := stmt.Exec(%s); err != nil", srcFieldPtrs.String()).
-		Printfln("return err").
-		CloseAndReopen("else").
-		Printfln("return nil").
-		Close()
-	method.Close()
-
-	g.sw.AddNewline()
-	method = g.sw.NewCompoundStatement("func (t *%[1]sQueryTx) Update(obj *%[1]s) error", g._type.name)
-	method.
-		Printfln("stmt := t.tx.Stmt(t.q.update)").
-		NewCompoundStatement("if _, err := stmt.Exec(%s); err != nil", srcFieldPtrs.String()).
-		Printfln("return err").
-		CloseAndReopen("else").
-		Printfln("return nil").
-		Close()
-	method.Close()
-
-	g.sw.AddNewline()
-	method = g.sw.NewCompoundStatement("func (t *%[1]sQueryTx) Delete(obj *%[1]s) error", g._type.name)
-	method.
-		Printfln("stmt := t.tx.Stmt(t.q.delete)").
-		NewCompoundStatement("if _, err := stmt.Exec(%s); err != nil", pkSrcFieldPtrs.String()).
-		Printfln("return err").
-		CloseAndReopen("else").
-		Printfln("return nil").
-		Close()
-	method.Close()
-}
-
-func (g *Generator) printCreateTransaction() {
-	method := g.sw.NewCompoundStatement("func (q *%[1]sQuery) Transaction() (*%[1]sQueryTx, error)", g._type.name)
-	method.NewCompoundStatement("if tx, err := q.db.Begin(); err != nil").
-		Printfln("return nil, err").
-		CloseAndReopen("else").
-		Printfln("return &%sQueryTx{tx: tx, q: q}, nil", g._type.name).
-		Close()
-	method.Close()
-
-	g.sw.AddNewline()
-	g.sw.NewCompoundStatement("func (t *%[1]sQueryTx) Commit() error", g._type.name).
-		Printfln("return t.tx.Commit()").
-		Close()
-
-	g.sw.AddNewline()
-	g.sw.NewCompoundStatement("func (t *%[1]sQueryTx) Rollback() error", g._type.name).
-		Printfln("return t.tx.Rollback()").
-		Close()
+	return formatSource(&g.buf)
 }
 
-func (g *Generator) Generate() {
-	g.printFileHeader()
-	g.sw.AddNewline()
-	g.printQueryDeclaration()
-	g.sw.AddNewline()
-	g.printSchemaValidation()
-	g.sw.AddNewline()
-	g.printCreateTransaction()
-	g.sw.AddNewline()
-	g.printInstanceCUD()
-	g.sw.Format()
+// Bytes returns the source produced by Generate.
+func (g *Generator) Bytes() []byte {
+	return g.buf.Bytes()
 }
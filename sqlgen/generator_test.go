@@ -9,25 +9,40 @@ import (
 )
 
 var _type = Type{
-	name:      "TypeName",
-	tableName: "tblName",
-	fields: []Field{
+	Name:  "TypeName",
+	Table: "tblName",
+	Fields: []Field{
 		Field{
-			srcName: "srcName",
-			dbName:  "dbName",
-			isPK:    true,
-			srcType: "int64",
-			dbType:  "BIGINT",
+			Name:   "srcName",
+			Column: "dbName",
+			IsPK:   true,
+			GoType: "int64",
+			DBType: "BIGINT",
 		},
 		Field{
-			srcName: "SrcName2",
-			dbName:  "dbName2",
-			isPK:    false,
-			srcType: "string",
-			dbType:  "VARCHAR",
+			Name:   "SrcName2",
+			Column: "dbName2",
+			IsPK:   false,
+			GoType: "string",
+			DBType: "VARCHAR",
 		},
 	},
-	packageName: "foopackage",
+	Indexes: []Index{
+		Index{
+			Name: "dbName2",
+			Fields: []Field{
+				Field{
+					Name:   "SrcName2",
+					Column: "dbName2",
+					IsPK:   false,
+					GoType: "string",
+					DBType: "VARCHAR",
+				},
+			},
+			Unique: false,
+		},
+	},
+	Package: "foopackage",
 }
 
 // TODO: Tests
@@ -60,13 +75,13 @@ func stringDelta(expected string, actual string) string {
 		}
 	}
 
-	for i = i; i < len(eLines); i++ {
+	for ; i < len(eLines); i++ {
 		output.WriteString("-- ")
 		output.WriteString(eLines[i])
 		output.WriteByte('\n')
 	}
 
-	for i = i; i < len(aLines); i++ {
+	for ; i < len(aLines); i++ {
 		output.WriteString("++ ")
 		output.WriteString(aLines[i])
 		output.WriteByte('\n')
@@ -75,153 +90,211 @@ func stringDelta(expected string, actual string) string {
 	return output.String()
 }
 
+// execute runs the template name on g, failing the test on error.
+func execute(t *testing.T, g interface{ execute(string) error }, name string) {
+	if err := g.execute(name); err != nil {
+		t.Fatalf("Error executing template %s: %s", name, err)
+	}
+}
+
 func TestPrintAdditionalImports(t *testing.T) {
-	g := &Generator{additionalImports: []string{"time", "foo"}, sw: new(SourceWriter), _type: Type{packageName: "fpkg"}}
+	g := &Generator{_type: Type{Package: "fpkg", Imports: []string{"time", "foo"}}}
 	expectedImports := `// generated by sqlgen; DO NOT EDIT
 
 package fpkg
 
+import "context"
 import "database/sql"
 import "time"
 import "foo"
+
+import "github.com/anupcshan/sqlgen/sqlrt"
 `
-	g.printFileHeader()
-	if actualImports := g.sw.buf.String(); actualImports != expectedImports {
+	execute(t, g, "header")
+	if actualImports := g.buf.String(); actualImports != expectedImports {
 		t.Fatalf("Mismatch in imports str:\n%s\n", stringDelta(expectedImports, actualImports))
 	}
 }
 
 func TestQueryDeclaration(t *testing.T) {
 	g := &Generator{
-		additionalImports: []string{"time", "foo"},
-		_type:             _type,
-		sw:                new(SourceWriter),
+		_type: _type,
 	}
 
 	expectedQueryDecl := `type TypeNameQuery struct {
+	TypeNameExecutor
 	db *sql.DB
-	create *sql.Stmt
-	bysrcName *sql.Stmt
-	bySrcName2 *sql.Stmt
-	delete *sql.Stmt
-	update *sql.Stmt
+	dialect sqlrt.Dialect
+	logger sqlrt.Logger
+	create *sqlrt.Stmt
+	bysrcName *sqlrt.Stmt
+	delete *sqlrt.Stmt
+	update *sqlrt.Stmt
 }
 
 type TypeNameQueryTx struct {
+	TypeNameExecutor
 	tx *sql.Tx
+}
+
+// TypeNameExecutor runs the operations of TypeNameQuery on a sqlrt.DBTX.
+type TypeNameExecutor struct {
+	exec sqlrt.DBTX
 	q *TypeNameQuery
 }
 `
-	g.printQueryDeclaration()
-	if actualQueryDecl := g.sw.buf.String(); actualQueryDecl != expectedQueryDecl {
+	execute(t, g, "declaration")
+	if actualQueryDecl := g.buf.String(); actualQueryDecl != expectedQueryDecl {
 		t.Fatalf("Mismatch in query declaration str:\n%s\n", stringDelta(expectedQueryDecl, actualQueryDecl))
 	}
 }
 
-func TestPrintSchemaValidation(t *testing.T) {
+func TestPrintConstructor(t *testing.T) {
 	g := &Generator{
-		additionalImports: []string{"time", "foo"},
-		_type:             _type,
-		sw:                new(SourceWriter),
+		_type: _type,
 	}
 
-	expectedSchemaVal := `func (q *TypeNameQuery) Validate() error {
-	if stmt, err := q.db.Prepare("INSERT INTO tblName(dbName,dbName2) VALUES($1,$2)"); err != nil {
-		return err
-	} else {
-		q.create = stmt
-	}
+	expectedConstructorStr := `func newTypeNameQuery(db *sql.DB, o sqlrt.Options) *TypeNameQuery {
+	q := &TypeNameQuery{db: db, dialect: o.Dialect, logger: o.Logger}
+	q.init()
+	return q
+}
 
-	if stmt, err := q.db.Prepare("SELECT dbName,dbName2 FROM tblName WHERE dbName=$1"); err != nil {
-		return err
-	} else {
-		q.bysrcName = stmt
+// NewTypeNameQuery returns the queries of TypeName on db, configured by opts.
+// Statements are prepared on first use, unless sqlrt.WithWarmup is given.
+func NewTypeNameQuery(db *sql.DB, opts ...sqlrt.Option) (*TypeNameQuery, error) {
+	o := sqlrt.NewOptions(opts...)
+	q := newTypeNameQuery(db, o)
+	if !o.Warmup {
+		return q, nil
 	}
-
-	if stmt, err := q.db.Prepare("SELECT dbName,dbName2 FROM tblName WHERE dbName2=$1"); err != nil {
-		return err
-	} else {
-		q.bySrcName2 = stmt
+	if err := q.Warmup(context.Background()); err != nil {
+		q.Close()
+		return nil, err
 	}
-
-	if stmt, err := q.db.Prepare("UPDATE tblName SET (dbName2)=($2) WHERE dbName=$1"); err != nil {
-		return err
-	} else {
-		q.update = stmt
+	return q, nil
+}
+`
+	execute(t, g, "constructor")
+	if actualConstructorStr := g.buf.String(); actualConstructorStr != expectedConstructorStr {
+		t.Fatalf("Mismatch in constructor str:\n%s\n", stringDelta(expectedConstructorStr, actualConstructorStr))
 	}
+}
 
-	if stmt, err := q.db.Prepare("DELETE FROM tblName WHERE dbName=$1"); err != nil {
-		return err
-	} else {
-		q.delete = stmt
+func TestPrintSchemaValidation(t *testing.T) {
+	g := &Generator{
+		_type: _type,
 	}
 
-	return nil
+	expectedSchemaVal := `// init sets up the statements of q, which are prepared on first use.
+func (q *TypeNameQuery) init() {
+	q.TypeNameExecutor = TypeNameExecutor{exec: q.db, q: q}
+	q.create = sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "INSERT INTO tblName(dbName,dbName2) VALUES($1,$2)"))
+	q.bysrcName = sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "SELECT dbName,dbName2 FROM tblName WHERE dbName=$1"))
+	q.update = sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "UPDATE tblName SET dbName2=$1 WHERE dbName=$2"))
+	q.delete = sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "DELETE FROM tblName WHERE dbName=$1"))
+}
+
+// Validate prepares every statement of q, checking them against the schema.
+func (q *TypeNameQuery) Validate() error {
+	return q.Warmup(context.Background())
+}
+
+// Warmup prepares every statement of q ahead of its first use.
+func (q *TypeNameQuery) Warmup(ctx context.Context) error {
+	return sqlrt.PrepareAll(ctx, q.create, q.bysrcName, q.update, q.delete)
+}
+
+// Close releases the prepared statements of q.
+func (q *TypeNameQuery) Close() error {
+	return sqlrt.CloseAll(q.create, q.bysrcName, q.update, q.delete)
 }
 `
 
-	g.printSchemaValidation()
-	if actualSchemaVal := g.sw.buf.String(); actualSchemaVal != expectedSchemaVal {
+	execute(t, g, "schemaValidation")
+	if actualSchemaVal := g.buf.String(); actualSchemaVal != expectedSchemaVal {
 		t.Fatalf("Mismatch in schema validation str:\n%s\n", stringDelta(expectedSchemaVal, actualSchemaVal))
 	}
 }
 
 func TestCreateInstance(t *testing.T) {
 	g := &Generator{
-		additionalImports: []string{"time", "foo"},
-		_type:             _type,
-		sw:                new(SourceWriter),
+		_type: _type,
 	}
 
-	expectedCreateInstStr := `func (t *TypeNameQueryTx) Create(obj *TypeName) error {
-	stmt := t.tx.Stmt(t.q.create)
-	if _, err := stmt.Exec(&obj.srcName, &obj.SrcName2); err != nil {
-		return err
+	expectedCreateInstStr := `func (e *TypeNameExecutor) Create(ctx context.Context, obj *TypeName) error {
+	if hook, ok := interface{}(obj).(sqlrt.BeforeCreater); ok {
+		if err := hook.BeforeCreate(); err != nil {
+			return err
+		}
+	}
+	if _, err := e.q.create.ExecContext(ctx, e.exec, &obj.srcName, &obj.SrcName2); err != nil {
+		return sqlrt.WrapError(e.q.dialect, "tblName", map[string]interface{}{"dbName": obj.srcName}, err)
 	} else {
 		return nil
 	}
 }
 
-func (t *TypeNameQueryTx) Update(obj *TypeName) error {
-	stmt := t.tx.Stmt(t.q.update)
-	if _, err := stmt.Exec(&obj.srcName, &obj.SrcName2); err != nil {
-		return err
+func (e *TypeNameExecutor) Update(ctx context.Context, obj *TypeName) error {
+	if hook, ok := interface{}(obj).(sqlrt.BeforeUpdater); ok {
+		if err := hook.BeforeUpdate(); err != nil {
+			return err
+		}
+	}
+	if _, err := e.q.update.ExecContext(ctx, e.exec, &obj.SrcName2, &obj.srcName); err != nil {
+		return sqlrt.WrapError(e.q.dialect, "tblName", map[string]interface{}{"dbName": obj.srcName}, err)
 	} else {
 		return nil
 	}
 }
 
-func (t *TypeNameQueryTx) Delete(obj *TypeName) error {
-	stmt := t.tx.Stmt(t.q.delete)
-	if _, err := stmt.Exec(&obj.srcName); err != nil {
-		return err
+func (e *TypeNameExecutor) Delete(ctx context.Context, obj *TypeName) error {
+	if hook, ok := interface{}(obj).(sqlrt.BeforeDeleter); ok {
+		if err := hook.BeforeDelete(); err != nil {
+			return err
+		}
+	}
+	if _, err := e.q.delete.ExecContext(ctx, e.exec, &obj.srcName); err != nil {
+		return sqlrt.WrapError(e.q.dialect, "tblName", map[string]interface{}{"dbName": obj.srcName}, err)
 	} else {
 		return nil
 	}
 }
 `
 
-	g.printInstanceCUD()
-	if actualCreateInstStr := g.sw.buf.String(); actualCreateInstStr != expectedCreateInstStr {
+	execute(t, g, "cud")
+	if actualCreateInstStr := g.buf.String(); actualCreateInstStr != expectedCreateInstStr {
 		t.Fatalf("Mismatch in create instance str:\n%s\n", stringDelta(expectedCreateInstStr, actualCreateInstStr))
 	}
 }
 
 func TestCreateTransaction(t *testing.T) {
 	g := &Generator{
-		additionalImports: []string{"time", "foo"},
-		_type:             _type,
-		sw:                new(SourceWriter),
+		_type: _type,
 	}
 
 	expectedCreateTxnStr := `func (q *TypeNameQuery) Transaction() (*TypeNameQueryTx, error) {
 	if tx, err := q.db.Begin(); err != nil {
 		return nil, err
 	} else {
-		return &TypeNameQueryTx{tx: tx, q: q}, nil
+		return &TypeNameQueryTx{TypeNameExecutor: TypeNameExecutor{exec: tx, q: q}, tx: tx}, nil
 	}
 }
 
+// InTx runs fn in a transaction, committed if fn succeeds and rolled back
+// otherwise. See sqlrt.InTx.
+func (q *TypeNameQuery) InTx(ctx context.Context, opts *sqlrt.TxOptions, fn func(tx *TypeNameQueryTx) error) error {
+	return sqlrt.InTx(ctx, q.db, q.dialect, opts, func(tx *sql.Tx) error {
+		return fn(&TypeNameQueryTx{TypeNameExecutor: TypeNameExecutor{exec: tx, q: q}, tx: tx})
+	})
+}
+
+// With returns the operations of TypeNameQuery running on db, such as a transaction
+// or connection owned by the caller.
+func (q *TypeNameQuery) With(db sqlrt.DBTX) *TypeNameExecutor {
+	return &TypeNameExecutor{exec: db, q: q}
+}
+
 func (t *TypeNameQueryTx) Commit() error {
 	return t.tx.Commit()
 }
@@ -229,25 +302,381 @@ func (t *TypeNameQueryTx) Commit() error {
 func (t *TypeNameQueryTx) Rollback() error {
 	return t.tx.Rollback()
 }
+
+func (t *TypeNameQueryTx) Savepoint(name string) error {
+	return sqlrt.Savepoint(context.Background(), t.tx, name)
+}
+
+func (t *TypeNameQueryTx) RollbackTo(name string) error {
+	return sqlrt.RollbackTo(context.Background(), t.tx, name)
+}
+
+func (t *TypeNameQueryTx) Release(name string) error {
+	return sqlrt.Release(context.Background(), t.tx, name)
+}
+
+// InTx runs fn within a savepoint of the transaction, released if fn succeeds
+// and rolled back to otherwise. opts are ignored, as the enclosing transaction
+// determines the isolation and cannot be retried from within.
+func (t *TypeNameQueryTx) InTx(ctx context.Context, opts *sqlrt.TxOptions, fn func(tx *TypeNameQueryTx) error) error {
+	return sqlrt.InSavepoint(ctx, t.tx, func() error {
+		return fn(t)
+	})
+}
 `
 
-	g.printCreateTransaction()
-	if actualCreateTxnStr := g.sw.buf.String(); actualCreateTxnStr != expectedCreateTxnStr {
+	execute(t, g, "transaction")
+	if actualCreateTxnStr := g.buf.String(); actualCreateTxnStr != expectedCreateTxnStr {
 		t.Fatalf("Mismatch in create txn str:\n%s\n", stringDelta(expectedCreateTxnStr, actualCreateTxnStr))
 	}
 }
 
+func TestPrintColumns(t *testing.T) {
+	g := &Generator{
+		_type: _type,
+	}
+
+	expectedColumnsStr := `var TypeNameColumns = struct {
+	srcName sqlrt.Column[int64]
+	SrcName2 sqlrt.Column[string]
+} {
+	srcName: sqlrt.Column[int64]{Name: "dbName"},
+	SrcName2: sqlrt.Column[string]{Name: "dbName2"},
+}
+`
+
+	execute(t, g, "columns")
+	if actualColumnsStr := g.buf.String(); actualColumnsStr != expectedColumnsStr {
+		t.Fatalf("Mismatch in columns str:\n%s\n", stringDelta(expectedColumnsStr, actualColumnsStr))
+	}
+}
+
+func TestPrintFinders(t *testing.T) {
+	g := &Generator{
+		_type: _type,
+	}
+
+	expectedFindersStr := `func (e *TypeNameExecutor) BysrcName(ctx context.Context, srcName int64) (*TypeName, error) {
+	row := e.q.bysrcName.QueryRowContext(ctx, e.exec, srcName)
+	obj := new(TypeName)
+	if err := row.Scan(&obj.srcName, &obj.SrcName2); err == sql.ErrNoRows {
+		return nil, &sqlrt.Error{Table: "tblName", Key: map[string]interface{}{"dbName": srcName}, Err: ErrTypeNameNotFound}
+	} else if err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(obj).(sqlrt.AfterLoader); ok {
+		if err := hook.AfterLoad(); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+func (e *TypeNameExecutor) BySrcName2(ctx context.Context, SrcName2 string, page sqlrt.Page) ([]*TypeName, string, error) {
+	return e.Select().Where(TypeNameColumns.SrcName2.Eq(SrcName2)).Page(ctx, page)
+}
+`
+
+	execute(t, g, "finders")
+	if actualFindersStr := g.buf.String(); actualFindersStr != expectedFindersStr {
+		t.Fatalf("Mismatch in finders str:\n%s\n", stringDelta(expectedFindersStr, actualFindersStr))
+	}
+}
+
+func TestPrintUniqueIndexFinder(t *testing.T) {
+	uniqueType := _type
+	uniqueType.Indexes = []Index{
+		Index{
+			Name:   "srcs",
+			Fields: _type.Fields,
+			Unique: true,
+		},
+	}
+	g := &Generator{
+		_type: uniqueType,
+	}
+
+	expectedFinderStr := `func (e *TypeNameExecutor) BysrcNameAndSrcName2(ctx context.Context, srcName int64, SrcName2 string) (*TypeName, error) {
+	row := e.q.bysrcNameAndSrcName2.QueryRowContext(ctx, e.exec, srcName, SrcName2)
+	obj := new(TypeName)
+	if err := row.Scan(&obj.srcName, &obj.SrcName2); err == sql.ErrNoRows {
+		return nil, &sqlrt.Error{Table: "tblName", Key: map[string]interface{}{"dbName": srcName, "dbName2": SrcName2}, Err: ErrTypeNameNotFound}
+	} else if err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(obj).(sqlrt.AfterLoader); ok {
+		if err := hook.AfterLoad(); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+`
+
+	execute(t, g, "finders")
+	if actualFindersStr := g.buf.String(); !strings.HasSuffix(actualFindersStr, expectedFinderStr) {
+		t.Fatalf("Mismatch in finders str:\n%s\n", actualFindersStr)
+	}
+
+	g.buf.Reset()
+	execute(t, g, "schemaValidation")
+	expectedStmt := `sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "SELECT dbName,dbName2 FROM tblName WHERE dbName=$1 AND dbName2=$2"))`
+	if actualSchemaVal := g.buf.String(); !strings.Contains(actualSchemaVal, expectedStmt) {
+		t.Fatalf("Missing unique index statement in schema validation str:\n%s\n", actualSchemaVal)
+	}
+}
+
+func TestPrintCountFinders(t *testing.T) {
+	g := &Generator{
+		_type: _type,
+	}
+
+	expectedCountFindersStr := `func (e *TypeNameExecutor) CountBysrcName(ctx context.Context, srcName int64) (int64, error) {
+	return e.Select().Where(TypeNameColumns.srcName.Eq(srcName)).Count(ctx)
+}
+
+func (e *TypeNameExecutor) ExistsBysrcName(ctx context.Context, srcName int64) (bool, error) {
+	return e.Select().Where(TypeNameColumns.srcName.Eq(srcName)).Exists(ctx)
+}
+
+func (e *TypeNameExecutor) CountBySrcName2(ctx context.Context, SrcName2 string) (int64, error) {
+	return e.Select().Where(TypeNameColumns.SrcName2.Eq(SrcName2)).Count(ctx)
+}
+
+func (e *TypeNameExecutor) ExistsBySrcName2(ctx context.Context, SrcName2 string) (bool, error) {
+	return e.Select().Where(TypeNameColumns.SrcName2.Eq(SrcName2)).Exists(ctx)
+}
+`
+
+	execute(t, g, "countFinders")
+	if actualCountFindersStr := g.buf.String(); actualCountFindersStr != expectedCountFindersStr {
+		t.Fatalf("Mismatch in count finders str:\n%s\n", stringDelta(expectedCountFindersStr, actualCountFindersStr))
+	}
+}
+
+func TestPrintAggregatesNumericOnly(t *testing.T) {
+	g := &Generator{
+		_type: _type,
+	}
+
+	execute(t, g, "aggregates")
+	actualAggregatesStr := g.buf.String()
+	if !strings.Contains(actualAggregatesStr, "func (s *TypeNameSelect) MaxsrcName(ctx context.Context) (int64, error) {") {
+		t.Fatalf("Missing aggregate for numeric column:\n%s\n", actualAggregatesStr)
+	}
+	if strings.Contains(actualAggregatesStr, "SumSrcName2") {
+		t.Fatalf("Unexpected aggregate for string column:\n%s\n", actualAggregatesStr)
+	}
+}
+
+func TestVersionedUpdate(t *testing.T) {
+	versionedType := _type
+	versionedType.Fields = append(append([]Field{}, _type.Fields...), Field{
+		Name:      "Version",
+		Column:    "version",
+		IsVersion: true,
+		GoType:    "int64",
+		DBType:    "BIGINT",
+	})
+	g := &Generator{
+		_type: versionedType,
+	}
+
+	execute(t, g, "schemaValidation")
+	expectedStmt := `sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "UPDATE tblName SET dbName2=$1,version=version+1 WHERE dbName=$2 AND version=$3"))`
+	if actualSchemaVal := g.buf.String(); !strings.Contains(actualSchemaVal, expectedStmt) {
+		t.Fatalf("Missing versioned update statement in schema validation str:\n%s\n", actualSchemaVal)
+	}
+
+	expectedUpdateStr := `func (e *TypeNameExecutor) Update(ctx context.Context, obj *TypeName) error {
+	if hook, ok := interface{}(obj).(sqlrt.BeforeUpdater); ok {
+		if err := hook.BeforeUpdate(); err != nil {
+			return err
+		}
+	}
+	if res, err := e.q.update.ExecContext(ctx, e.exec, &obj.SrcName2, &obj.srcName, &obj.Version); err != nil {
+		return sqlrt.WrapError(e.q.dialect, "tblName", map[string]interface{}{"dbName": obj.srcName}, err)
+	} else if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &sqlrt.Error{Table: "tblName", Key: map[string]interface{}{"dbName": obj.srcName, "version": obj.Version}, Err: sqlrt.ErrStaleObject}
+	}
+	obj.Version++
+	return nil
+}
+`
+	g.buf.Reset()
+	execute(t, g, "cud")
+	if actualCUDStr := g.buf.String(); !strings.Contains(actualCUDStr, expectedUpdateStr) {
+		t.Fatalf("Mismatch in versioned update:\n%s\n", stringDelta(expectedUpdateStr, actualCUDStr))
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	softDeleteType := _type
+	softDeleteType.Fields = append(append([]Field{}, _type.Fields...), Field{
+		Name:         "Deleted",
+		Column:       "deleted",
+		IsSoftDelete: true,
+		GoType:       "*time.Time",
+		DBType:       "TIMESTAMP",
+	})
+	g := &Generator{
+		_type: softDeleteType,
+	}
+
+	execute(t, g, "schemaValidation")
+	actualSchemaVal := g.buf.String()
+	for _, expectedStmt := range []string{
+		`q.bysrcName = sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "SELECT dbName,dbName2,deleted FROM tblName WHERE dbName=$1 AND deleted IS NULL"))`,
+		`q.delete = sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "UPDATE tblName SET deleted=$1 WHERE dbName=$2 AND deleted IS NULL"))`,
+		`q.hardDelete = sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "DELETE FROM tblName WHERE dbName=$1"))`,
+		`q.update = sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "UPDATE tblName SET dbName2=$1 WHERE dbName=$2 AND deleted IS NULL"))`,
+	} {
+		if !strings.Contains(actualSchemaVal, expectedStmt) {
+			t.Fatalf("Missing %s in schema validation str:\n%s\n", expectedStmt, actualSchemaVal)
+		}
+	}
+
+	expectedUpdateStr := `	if res, err := e.q.update.ExecContext(ctx, e.exec, &obj.SrcName2, &obj.srcName); err != nil {
+		return sqlrt.WrapError(e.q.dialect, "tblName", map[string]interface{}{"dbName": obj.srcName}, err)
+	} else if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &sqlrt.Error{Table: "tblName", Key: map[string]interface{}{"dbName": obj.srcName}, Err: ErrTypeNameNotFound}
+	}
+	return nil
+}
+`
+	expectedDeleteStr := `func (e *TypeNameExecutor) Delete(ctx context.Context, obj *TypeName) error {
+	if hook, ok := interface{}(obj).(sqlrt.BeforeDeleter); ok {
+		if err := hook.BeforeDelete(); err != nil {
+			return err
+		}
+	}
+	now := e.q.timeNow()
+	if _, err := e.q.delete.ExecContext(ctx, e.exec, now, &obj.srcName); err != nil {
+		return sqlrt.WrapError(e.q.dialect, "tblName", map[string]interface{}{"dbName": obj.srcName}, err)
+	}
+	obj.Deleted = &now
+	return nil
+}
+
+func (e *TypeNameExecutor) HardDelete(ctx context.Context, obj *TypeName) error {
+	if hook, ok := interface{}(obj).(sqlrt.BeforeDeleter); ok {
+		if err := hook.BeforeDelete(); err != nil {
+			return err
+		}
+	}
+	if _, err := e.q.hardDelete.ExecContext(ctx, e.exec, &obj.srcName); err != nil {
+`
+	g.buf.Reset()
+	execute(t, g, "cud")
+	if actualCUDStr := g.buf.String(); !strings.Contains(actualCUDStr, expectedUpdateStr) {
+		t.Fatalf("Mismatch in soft delete update:\n%s\n", actualCUDStr)
+	} else if !strings.Contains(actualCUDStr, expectedDeleteStr) {
+		t.Fatalf("Mismatch in soft delete:\n%s\n", stringDelta(expectedDeleteStr, actualCUDStr))
+	}
+
+	g.buf.Reset()
+	execute(t, g, "selectBuilder")
+	if actualSelectStr := g.buf.String(); !strings.Contains(actualSelectStr, "sel.Scope = []sqlrt.Predicate{TypeNameColumns.Deleted.IsNull()}") {
+		t.Fatalf("Missing soft delete scope in select builder:\n%s\n", actualSelectStr)
+	}
+
+	g.buf.Reset()
+	execute(t, g, "finders")
+	expectedFinderStr := `func (e *TypeNameExecutor) BySrcName2WithDeleted(ctx context.Context, SrcName2 string, page sqlrt.Page) ([]*TypeName, string, error) {
+	return e.Select().WithDeleted().Where(TypeNameColumns.SrcName2.Eq(SrcName2)).Page(ctx, page)
+}
+`
+	if actualFindersStr := g.buf.String(); !strings.HasSuffix(actualFindersStr, expectedFinderStr) {
+		t.Fatalf("Mismatch in finders str:\n%s\n", actualFindersStr)
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	timestampType := _type
+	timestampType.Fields = append(append([]Field{}, _type.Fields...),
+		Field{
+			Name:      "Created",
+			Column:    "created",
+			IsCreated: true,
+			GoType:    "time.Time",
+			DBType:    "TIMESTAMP",
+		},
+		Field{
+			Name:      "Updated",
+			Column:    "updated",
+			IsUpdated: true,
+			GoType:    "time.Time",
+			DBType:    "TIMESTAMP",
+		})
+	g := &Generator{
+		_type: timestampType,
+	}
+
+	execute(t, g, "schemaValidation")
+	expectedStmt := `sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "UPDATE tblName SET dbName2=$1,updated=$2 WHERE dbName=$3"))`
+	if actualSchemaVal := g.buf.String(); !strings.Contains(actualSchemaVal, expectedStmt) {
+		t.Fatalf("Missing update statement in schema validation str:\n%s\n", actualSchemaVal)
+	}
+
+	expectedCUDStr := `func (e *TypeNameExecutor) Create(ctx context.Context, obj *TypeName) error {
+	if hook, ok := interface{}(obj).(sqlrt.BeforeCreater); ok {
+		if err := hook.BeforeCreate(); err != nil {
+			return err
+		}
+	}
+	now := e.q.timeNow()
+	obj.Created = now
+	obj.Updated = now
+	if _, err := e.q.create.ExecContext(ctx, e.exec, &obj.srcName, &obj.SrcName2, &obj.Created, &obj.Updated); err != nil {
+		return sqlrt.WrapError(e.q.dialect, "tblName", map[string]interface{}{"dbName": obj.srcName}, err)
+	} else {
+		return nil
+	}
+}
+
+func (e *TypeNameExecutor) Update(ctx context.Context, obj *TypeName) error {
+	if hook, ok := interface{}(obj).(sqlrt.BeforeUpdater); ok {
+		if err := hook.BeforeUpdate(); err != nil {
+			return err
+		}
+	}
+	obj.Updated = e.q.timeNow()
+	if _, err := e.q.update.ExecContext(ctx, e.exec, &obj.SrcName2, &obj.Updated, &obj.srcName); err != nil {
+`
+	g.buf.Reset()
+	execute(t, g, "cud")
+	if actualCUDStr := g.buf.String(); !strings.HasPrefix(actualCUDStr, expectedCUDStr) {
+		t.Fatalf("Mismatch in timestamped create/update:\n%s\n", stringDelta(expectedCUDStr, actualCUDStr))
+	}
+
+	g.buf.Reset()
+	execute(t, g, "declaration")
+	if actualQueryDecl := g.buf.String(); !strings.Contains(actualQueryDecl, "now func() time.Time") {
+		t.Fatalf("Missing clock in query declaration:\n%s\n", actualQueryDecl)
+	}
+
+	g.buf.Reset()
+	execute(t, g, "constructor")
+	if actualConstructorStr := g.buf.String(); !strings.Contains(actualConstructorStr, "q.now = o.Now") {
+		t.Fatalf("Missing clock option in constructor:\n%s\n", actualConstructorStr)
+	}
+}
+
 func TestGenerate(t *testing.T) {
 	g := &Generator{
-		// additionalImports: []string{"time", "foo"},
 		_type: _type,
-		sw:    new(SourceWriter),
 	}
 
 	expectedBytes, _ := ioutil.ReadFile("testdata/generated.go")
 	expectedStr := string(expectedBytes)
-	g.Generate()
-	if actualStr := g.sw.buf.String(); actualStr != expectedStr {
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	if actualStr := g.buf.String(); actualStr != expectedStr {
 		t.Fatalf("Mismatch in file contents:\n%s\n", stringDelta(expectedStr, actualStr))
 	}
 }
@@ -0,0 +1,136 @@
+package sqlgen
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// Type is a struct type stored in a table of the database, as extracted by the
+// Parser. Generated code and templates are produced from it, and it encodes to
+// JSON for tools consuming the model.
+type Type struct {
+	Name      string     `json:"name"`                // Type name in source
+	Table     string     `json:"table"`               // Table name in DB
+	Package   string     `json:"package"`             // Package that new type should go into
+	PkgName   string     `json:"pkgName,omitempty"`   // Name of the package declaring the type
+	PkgPath   string     `json:"pkgPath,omitempty"`   // Import path of the package declaring the type
+	Dir       string     `json:"dir,omitempty"`       // Directory of the package declaring the type
+	Fields    []Field    `json:"fields"`              // List of fields synced with DB, in column order
+	Indexes   []Index    `json:"indexes,omitempty"`   // Indexes declared on the type, excluding the primary key
+	Relations []Relation `json:"relations,omitempty"` // Fields referring to other types, which are not stored
+	Imports   []string   `json:"imports,omitempty"`   // Paths of the packages referenced by field types, or by the generated code
+}
+
+// Field is a field of a Type, stored in a column of its table.
+type Field struct {
+	Name         string `json:"name"`                 // Field name in source
+	Column       string `json:"column"`               // Column name in DB
+	GoType       string `json:"goType"`               // Field type in source
+	DBType       string `json:"dbType"`               // Expected column type in the DB
+	IsPK         bool   `json:"pk,omitempty"`         // Is the field a primary key?
+	IsVersion    bool   `json:"version,omitempty"`    // Is the field the row version, for optimistic locking?
+	IsSoftDelete bool   `json:"softDelete,omitempty"` // Is the field the deletion time of soft deleted rows?
+	IsCreated    bool   `json:"created,omitempty"`    // Is the field the creation time, set by Create?
+	IsUpdated    bool   `json:"updated,omitempty"`    // Is the field the last update time, set by Create and Update?
+}
+
+// Index is a set of columns declared as an index. A finder is generated for
+// each index.
+type Index struct {
+	Name   string  `json:"name"`             // Index name, as declared in the struct tags
+	Fields []Field `json:"fields"`           // Columns in the index, in declaration order
+	Unique bool    `json:"unique,omitempty"` // Does the index identify at most one row?
+}
+
+// Relation is a field of a Type referring to another struct type, such as a
+// foreign object or a list of dependent objects. Relations are not stored, and
+// no code is generated for them yet.
+type Relation struct {
+	Field  string `json:"field"`          // Field name in source
+	Target string `json:"target"`         // Referred type, as named in source
+	Many   bool   `json:"many,omitempty"` // Does the field hold a list of the target type?
+}
+
+// FinderName is the suffix of the finder method and statement for the index.
+func (idx Index) FinderName() string {
+	names := make([]string, len(idx.Fields))
+	for i, field := range idx.Fields {
+		names[i] = field.Name
+	}
+	return strings.Join(names, "And")
+}
+
+// GoType returns the type as referred to by the generated code, qualified by
+// the package declaring it if the code goes into another package.
+func (t *Type) GoType() string {
+	if t.PkgName == "" || t.PkgName == t.Package {
+		return t.Name
+	}
+	return t.PkgName + "." + t.Name
+}
+
+// SetPackage makes the code generated for the type go into the named package
+// rather than the one declaring it, which is then imported. The fields must be
+// exported, for the generated code to read and write them.
+func (t *Type) SetPackage(name string) error {
+	if name == t.PkgName {
+		return fmt.Errorf("type %s: package %s is named like %s, which declares the type", t.Name, name, t.PkgPath)
+	}
+	for _, field := range t.Fields {
+		if !ast.IsExported(field.Name) {
+			return fmt.Errorf("type %s: field %s is not exported, to be used by package %s", t.Name, field.Name, name)
+		}
+	}
+	t.Package = name
+	t.Imports = append(t.Imports, t.PkgPath)
+	return nil
+}
+
+// PK returns the primary key of the type.
+func (t *Type) PK() Field {
+	var pk *Field
+	for i, field := range t.Fields {
+		if field.IsPK {
+			if pk != nil {
+				panic("Multiple primary key columns not implemented.")
+			}
+			pk = &t.Fields[i]
+		}
+	}
+	if pk == nil {
+		panic("Type without primary key not implemented.")
+	}
+	return *pk
+}
+
+// Version returns the version column of the type, or nil if it has none.
+func (t *Type) Version() *Field {
+	return t.findField(func(f Field) bool { return f.IsVersion })
+}
+
+// SoftDelete returns the deletion time column of the type, or nil if rows
+// are deleted physically.
+func (t *Type) SoftDelete() *Field {
+	return t.findField(func(f Field) bool { return f.IsSoftDelete })
+}
+
+// Created returns the creation time column of the type, if any.
+func (t *Type) Created() *Field {
+	return t.findField(func(f Field) bool { return f.IsCreated })
+}
+
+// Updated returns the last update time column of the type, if any.
+func (t *Type) Updated() *Field {
+	return t.findField(func(f Field) bool { return f.IsUpdated })
+}
+
+// findField returns the first field of the type matching pred, or nil.
+func (t *Type) findField(pred func(Field) bool) *Field {
+	for i, field := range t.Fields {
+		if pred(field) {
+			return &t.Fields[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package sqlgen
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTypeJSON(t *testing.T) {
+	typ := Type{
+		Name:    "Foo",
+		Table:   "foo",
+		Package: "model",
+		Fields: []Field{
+			Field{Name: "Id", Column: "id", GoType: "int64", DBType: "BIGINT", IsPK: true},
+			Field{Name: "Deleted", Column: "deleted", GoType: "*time.Time", DBType: "TIMESTAMP", IsSoftDelete: true},
+		},
+		Relations: []Relation{
+			Relation{Field: "Bars", Target: "Bar", Many: true},
+		},
+		Imports: []string{"time"},
+	}
+
+	expectedJSON := `{"name":"Foo","table":"foo","package":"model",` +
+		`"fields":[{"name":"Id","column":"id","goType":"int64","dbType":"BIGINT","pk":true},` +
+		`{"name":"Deleted","column":"deleted","goType":"*time.Time","dbType":"TIMESTAMP","softDelete":true}],` +
+		`"relations":[{"field":"Bars","target":"Bar","many":true}],"imports":["time"]}`
+	actualJSON, err := json.Marshal(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actualJSON) != expectedJSON {
+		t.Fatalf("Mismatch in JSON:\nexpected %s\nactual   %s", expectedJSON, actualJSON)
+	}
+
+	var decoded Type
+	if err := json.Unmarshal(actualJSON, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, typ) {
+		t.Fatalf("Mismatch in decoded type:\nexpected %+v\nactual   %+v", typ, decoded)
+	}
+}
+
+func TestTypeSetPackage(t *testing.T) {
+	typ := Type{
+		Name:    "Foo",
+		Package: "model",
+		PkgName: "model",
+		PkgPath: "example.com/model",
+		Fields: []Field{
+			Field{Name: "Id", Column: "id", GoType: "int64", DBType: "BIGINT", IsPK: true},
+		},
+		Imports: []string{"time"},
+	}
+	if typ.GoType() != "Foo" {
+		t.Fatalf("Unexpected Go type: %s", typ.GoType())
+	}
+
+	if err := typ.SetPackage("model"); err == nil {
+		t.Fatal("Expected error for package named like the declaring one")
+	}
+	if err := typ.SetPackage("queries"); err != nil {
+		t.Fatal(err)
+	}
+	if typ.Package != "queries" || typ.GoType() != "model.Foo" {
+		t.Fatalf("Unexpected type: %+v", typ)
+	}
+	if !reflect.DeepEqual(typ.Imports, []string{"time", "example.com/model"}) {
+		t.Fatalf("Unexpected imports: %v", typ.Imports)
+	}
+
+	unexported := Type{Name: "Bar", PkgName: "model", Fields: []Field{Field{Name: "id"}}}
+	if err := unexported.SetPackage("queries"); err == nil {
+		t.Fatal("Expected error for unexported field")
+	}
+}
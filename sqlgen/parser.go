@@ -3,60 +3,618 @@ package sqlgen
 import (
 	"fmt"
 	"go/ast"
-	"go/build"
-	"go/parser"
 	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/golang/glog"
-	"golang.org/x/tools/go/types"
+	"golang.org/x/tools/go/packages"
 )
 
 type File struct {
 	name       string
+	fset       *token.FileSet
+	pkg        *packages.Package // Package the file belongs to
 	parsedText *ast.File
 }
 
 type Parser struct {
-	pkg   *types.Package
-	dir   string
-	files []*File
+	fset     *token.FileSet
+	patterns []string // Patterns of the packages to parse
+	files    []*File
+	strict   bool            // Fail on fields which are skipped
+	options  map[string]bool // Accepted options of sqlgen tags; all if nil
+}
+
+// Error is an error in the parsed source, at the position it was found.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// ErrorList is a list of errors in the parsed source, one per line.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
 }
 
 func NewParser() *Parser {
-	return &Parser{files: []*File{}}
+	return &Parser{fset: token.NewFileSet(), files: []*File{}}
+}
+
+// SetStrict makes ParseType fail on fields it skips, rather than log them. The
+// error is an ErrorList of every skipped field and why. Fields tagged
+// `sqlgen:"-"` are always skipped silently.
+func (p *Parser) SetStrict(strict bool) {
+	p.strict = strict
 }
 
-func (p *Parser) AddDirectory(directory string) {
-	p.dir = directory
+// SetTagOptions limits the options of sqlgen struct tags accepted by ParseType
+// to options, for generators implementing only some of them. Fields tagged
+// with other options fail it with an *Error. The `sqlgen:"-"` tag is always
+// accepted.
+func (p *Parser) SetTagOptions(options ...string) {
+	p.options = make(map[string]bool)
+	for _, opt := range options {
+		p.options[opt] = true
+	}
+}
+
+// unsupportedOption returns the first option of opts, in sorted order, which
+// is not accepted, or "" if all of them are.
+func (p *Parser) unsupportedOption(opts tagOptions) string {
+	if p.options == nil {
+		return ""
+	}
+	var keys []string
+	for key := range opts {
+		if !p.options[key] {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+	return keys[0]
+}
+
+// AddDirectory adds the package in directory to those parsed by ParseFiles.
+func (p *Parser) AddDirectory(directory string) error {
 	glog.Infof("Adding directory: %s\n", directory)
-	glog.Infof("Source directories: %s\n", build.Default.SrcDirs())
+	if info, err := os.Stat(directory); err != nil {
+		return fmt.Errorf("importing directory: %s", err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("importing directory: %s is not a directory", directory)
+	}
 
-	pkg, err := build.ImportDir(directory, 0)
+	// Relative directories must start with a dot, not to be taken for import paths.
+	if !filepath.IsAbs(directory) && !strings.HasPrefix(directory, ".") {
+		directory = "." + string(filepath.Separator) + directory
+	}
+	p.AddPattern(directory)
+	return nil
+}
+
+// AddPattern adds the packages matching pattern to those parsed by ParseFiles.
+// Patterns are those of the go command, resolved in the current module: import
+// paths, directories, and either with "..." wildcards.
+func (p *Parser) AddPattern(pattern string) {
+	glog.Infof("Adding pattern: %s\n", pattern)
+	p.patterns = append(p.patterns, pattern)
+}
+
+// ParseFiles loads, parses and type-checks the added packages. The first error
+// loading them is returned, with syntax errors positioned like Error.
+func (p *Parser) ParseFiles() error {
+	config := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedImports | packages.NeedTypes,
+		Fset: p.fset,
+	}
+	pkgs, err := packages.Load(config, p.patterns...)
 	if err != nil {
-		glog.Fatalf("Error importing directory: %s\n", err)
+		return err
+	}
+
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return pkg.Errors[0]
+		}
+		for _, parsedFile := range pkg.Syntax {
+			name := p.fset.Position(parsedFile.Pos()).Filename
+			glog.Infof("Parsed file: %s\n", name)
+			p.files = append(p.files, &File{name: name, fset: p.fset, pkg: pkg, parsedText: parsedFile})
+		}
+	}
+	return nil
+}
+
+// knownSourceTypes maps the field types we know how to store to their DB type.
+var knownSourceTypes = map[string]string{
+	"int64":     "BIGINT",
+	"int":       "INTEGER",
+	"string":    "VARCHAR",
+	"time.Time": "TIMESTAMP",
+
+	// Nullable types
+	"*time.Time": "TIMESTAMP",
+}
+
+// ParseType extracts the named struct type from the parsed files. The name is
+// either qualified by the import path of the package declaring the type, as in
+// example.com/models.Account, or must be declared by a single package. Errors
+// in its declaration are returned as an *Error, and skipped fields in strict
+// mode as an ErrorList.
+func (p *Parser) ParseType(name string) (*Type, error) {
+	pkgPath, typeName := "", name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		pkgPath, typeName = name[:i], name[i+1:]
+	}
+
+	decls := p.lookupType(pkgPath, typeName)
+	if len(decls) == 0 {
+		return nil, fmt.Errorf("type %s not found", name)
+	} else if len(decls) > 1 {
+		return nil, fmt.Errorf("type %s declared by several packages: %s and %s", name, decls[0].file.pkg.PkgPath, decls[1].file.pkg.PkgPath)
+	}
+
+	file, tspec := decls[0].file, decls[0].spec
+	if _, ok := tspec.Type.(*ast.StructType); !ok {
+		return nil, file.errorf(tspec.Pos(), "type %s is not a struct", typeName)
+	}
+	return file.genType(p, tspec)
+}
+
+// TableDirective is the comment marking a type declaration as stored in a
+// table, for its queries to be generated without listing it.
+const TableDirective = "//sqlgen:table"
+
+// hasTableDirective reports whether the doc comment of a type declaration holds
+// the TableDirective, on a line of its own.
+func hasTableDirective(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
 	}
+	for _, comment := range doc.List {
+		if strings.TrimRight(comment.Text, " \t") == TableDirective {
+			return true
+		}
+	}
+	return false
+}
+
+// TableTypes returns the names of the types marked with the TableDirective in
+// the parsed files, in order of declaration, qualified by the import path of
+// their package.
+func (p *Parser) TableTypes() []string {
+	var names []string
+	for _, file := range p.files {
+		if file.parsedText == nil {
+			continue
+		}
+
+		for _, decl := range file.parsedText.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
 
-	// Currently, we don't include CgoFiles, TestGoFiles, SFiles etc...
-	p.files = make([]*File, len(pkg.GoFiles))
-	for i, name := range pkg.GoFiles {
-		fName := fmt.Sprintf("%s/%s", directory, name)
-		glog.Infof("Discovered file: %s\n", fName)
-		p.files[i] = new(File)
-		p.files[i].name = fName
+			for _, spec := range genDecl.Specs {
+				tspec := spec.(*ast.TypeSpec) // Guaranteed to succeed as this is TYPE.
+				// The comment of an ungrouped declaration is that of the GenDecl.
+				if hasTableDirective(tspec.Doc) || (!genDecl.Lparen.IsValid() && hasTableDirective(genDecl.Doc)) {
+					names = append(names, file.pkg.PkgPath+"."+tspec.Name.Name)
+				}
+			}
+		}
 	}
+	return names
+}
+
+// typeDecl is the declaration of a type, in a parsed file.
+type typeDecl struct {
+	file *File
+	spec *ast.TypeSpec
 }
 
-func (p *Parser) ParseFiles() {
-	fs := token.NewFileSet()
+// lookupType returns the declarations of the named type by the parsed package
+// with the import path pkgPath, or by any of them if pkgPath is empty.
+func (p *Parser) lookupType(pkgPath, name string) []typeDecl {
+	var decls []typeDecl
 	for _, file := range p.files {
-		glog.Infof("Parsing file: %s\n", file.name)
-		parsedFile, err := parser.ParseFile(fs, file.name, nil, 0)
+		if file.parsedText == nil || (pkgPath != "" && file.pkg.PkgPath != pkgPath) {
+			continue
+		}
+
+		for _, decl := range file.parsedText.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range genDecl.Specs {
+				tspec := spec.(*ast.TypeSpec) // Guaranteed to succeed as this is TYPE.
+				if tspec.Name.Name == name {
+					decls = append(decls, typeDecl{file: file, spec: tspec})
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// errorf returns an *Error at pos in the file.
+func (f *File) errorf(pos token.Pos, format string, args ...interface{}) error {
+	return &Error{Pos: f.position(pos), Msg: fmt.Sprintf(format, args...)}
+}
+
+// position returns the position of pos, with a file name relative to the
+// working directory if the file is below it, as packages are loaded with
+// absolute file names.
+func (f *File) position(pos token.Pos) token.Position {
+	position := f.fset.Position(pos)
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, position.Filename); err == nil && !strings.HasPrefix(rel, "..") {
+			position.Filename = rel
+		}
+	}
+	return position
+}
+
+// structField is a named field of a struct, or of a struct embedded in it.
+type structField struct {
+	v       *types.Var // The field, possibly promoted from an embedded struct
+	tag     string     // Struct tag of the field
+	prefix  string     // Column prefix of the embedded structs holding the field
+	ignored bool       // Tagged sqlgen:"-", to be left out
+}
+
+// flatten returns a structField for each named field of st, replacing embedded
+// structs, of any package, with their own fields. The columns of the fields of
+// an embedded struct get the prefix set by its `sqlgen:"prefix:..."` tag, after
+// that of the structs embedding it. Embedded fields which cannot be flattened
+// are returned as skipped.
+func (f *File) flatten(p *Parser, st *types.Struct, prefix string) ([]structField, ErrorList, error) {
+	var fields []structField
+	var skipped ErrorList
+	for i := 0; i < st.NumFields(); i++ {
+		v, tag := st.Field(i), st.Tag(i)
+		if !v.Embedded() {
+			fields = append(fields, structField{v: v, tag: tag, prefix: prefix, ignored: ignored(tag)})
+			continue
+		} else if ignored(tag) {
+			continue
+		}
+
+		opts, err := parseTag(tag)
 		if err != nil {
-			glog.Fatalf("Error parsing file: %s\n", err)
+			return nil, nil, f.errorf(v.Pos(), "field %s: %s", v.Name(), err)
+		}
+		for key := range opts {
+			if key != "prefix" {
+				return nil, nil, f.errorf(v.Pos(), "field %s: %s does not apply to embedded structs", v.Name(), key)
+			}
 		}
-		file.parsedText = parsedFile
-		for _, imp := range parsedFile.Imports {
-			glog.Infof("Import: %s\n", imp.Path.Value)
+		if opt := p.unsupportedOption(opts); opt != "" {
+			return nil, nil, f.errorf(v.Pos(), "field %s: sqlgen option %s is not supported", v.Name(), opt)
+		}
+
+		typeName := f.typeString(v.Type())
+		if _, ok := types.Unalias(v.Type()).(*types.Pointer); ok {
+			skipped = append(skipped, f.skip(v.Pos(), v.Name(), "embedded pointers are not supported"))
+			continue
+		} else if _, known := knownSourceTypes[typeName]; known {
+			skipped = append(skipped, f.skip(v.Pos(), v.Name(), "embedded %s is not supported", typeName))
+			continue
 		}
+		embedded, ok := v.Type().Underlying().(*types.Struct)
+		if !ok {
+			skipped = append(skipped, f.skip(v.Pos(), v.Name(), "%s is not a struct", typeName))
+			continue
+		}
+
+		var embeddedPrefix string
+		if values := opts.values("prefix"); len(values) > 0 {
+			embeddedPrefix = values[len(values)-1]
+		}
+		// Go rejects structs embedding themselves, so this terminates.
+		embeddedFields, embeddedSkipped, err := f.flatten(p, embedded, prefix+embeddedPrefix)
+		if err != nil {
+			return nil, nil, err
+		}
+		fields = append(fields, embeddedFields...)
+		skipped = append(skipped, embeddedSkipped...)
+	}
+	return fields, skipped, nil
+}
+
+// genType builds the Type for a struct declared in the file. Fields which are
+// skipped fail it in strict mode.
+func (f *File) genType(p *Parser, tspec *ast.TypeSpec) (*Type, error) {
+	name := tspec.Name.Name
+	t := &Type{
+		Name:    name,
+		Table:   strings.ToLower(name),
+		Package: f.pkg.Name,
+		PkgName: f.pkg.Name,
+		PkgPath: f.pkg.PkgPath,
+		Dir:     filepath.Dir(f.name),
+	}
+
+	obj := f.pkg.Types.Scope().Lookup(name)
+	if obj == nil {
+		return nil, f.errorf(tspec.Pos(), "type %s not found", name)
+	}
+	structType, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, f.errorf(tspec.Pos(), "type %s is not a struct", name)
+	}
+	fields, skipped, err := f.flatten(p, structType, "")
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make(map[string]*Index)
+	var indexNames []string
+	for _, sf := range fields {
+		v, fieldName := sf.v, sf.v.Name()
+		typeName := f.typeString(v.Type())
+
+		// Fields referring to structs of the package are relations, which are
+		// not stored, and skipped unless ignored.
+		if target, many, ok := f.relationTarget(v.Type()); ok {
+			// TODO: Relations likely mean foreign key links.
+			t.Relations = append(t.Relations, Relation{Field: fieldName, Target: target, Many: many})
+			if !sf.ignored {
+				skipped = append(skipped, f.skip(v.Pos(), fieldName, "relation to %s is not stored", target))
+			}
+			continue
+		} else if sf.ignored {
+			continue
+		}
+
+		dbType, ok := knownSourceTypes[typeName]
+		if !ok {
+			skipped = append(skipped, f.skip(v.Pos(), fieldName, "unsupported type %s", typeName))
+			continue
+		}
+
+		if v.Pkg() != f.pkg.Types && !v.Exported() {
+			// Promoted fields of other packages are only accessible if exported.
+			skipped = append(skipped, f.skip(v.Pos(), fieldName, "unexported field of another package"))
+			continue
+		}
+
+		opts, err := parseTag(sf.tag)
+		if err != nil {
+			return nil, f.errorf(v.Pos(), "field %s: %s", fieldName, err)
+		} else if opts.has("prefix") {
+			return nil, f.errorf(v.Pos(), "field %s: prefix applies to embedded structs only", fieldName)
+		} else if opt := p.unsupportedOption(opts); opt != "" {
+			return nil, f.errorf(v.Pos(), "field %s: sqlgen option %s is not supported", fieldName, opt)
+		}
+
+		if path := f.importPath(v.Type()); path != "" {
+			t.Imports = appendUnique(t.Imports, path)
+		}
+		column := Field{
+			Name:         fieldName,
+			Column:       sf.prefix + strings.ToLower(fieldName), // TODO: Override with annotations
+			IsPK:         strings.ToLower(fieldName) == "id" || opts.has("pk"),
+			IsVersion:    opts.has("version"),
+			IsSoftDelete: opts.has("softdelete"),
+			IsCreated:    opts.has("created"),
+			IsUpdated:    opts.has("updated"),
+			GoType:       typeName,
+			DBType:       dbType,
+		}
+
+		for _, kind := range []string{"created", "updated"} {
+			if !opts.has(kind) {
+				continue
+			}
+			if typeName != "time.Time" {
+				return nil, f.errorf(v.Pos(), "field %s: %s column must be a time.Time, not %s", fieldName, kind, typeName)
+			}
+			for _, other := range t.Fields {
+				if (kind == "created" && other.IsCreated) || (kind == "updated" && other.IsUpdated) {
+					return nil, f.errorf(v.Pos(), "field %s: %s column already declared by %s", fieldName, kind, other.Name)
+				}
+			}
+		}
+
+		if column.IsSoftDelete {
+			if typeName != "*time.Time" {
+				return nil, f.errorf(v.Pos(), "field %s: soft delete column must be a *time.Time, not %s", fieldName, typeName)
+			}
+			for _, other := range t.Fields {
+				if other.IsSoftDelete {
+					return nil, f.errorf(v.Pos(), "field %s: soft delete column already declared by %s", fieldName, other.Name)
+				}
+			}
+		}
+
+		for _, other := range t.Fields {
+			if other.Name == column.Name || other.Column == column.Column {
+				return nil, f.errorf(v.Pos(), "field %s: column %s already declared by %s", fieldName, column.Column, other.Name)
+			}
+		}
+
+		if column.IsPK {
+			for _, other := range t.Fields {
+				if other.IsPK {
+					return nil, f.errorf(v.Pos(), "field %s: primary key already declared by %s", fieldName, other.Name)
+				}
+			}
+		}
+
+		if column.IsVersion {
+			if typeName != "int64" && typeName != "int" {
+				return nil, f.errorf(v.Pos(), "field %s: version column must be an integer, not %s", fieldName, typeName)
+			}
+			for _, other := range t.Fields {
+				if other.IsVersion {
+					return nil, f.errorf(v.Pos(), "field %s: version column already declared by %s", fieldName, other.Name)
+				}
+			}
+		}
+		t.Fields = append(t.Fields, column)
+
+		for _, kind := range []string{"index", "unique"} {
+			for _, indexName := range opts.values(kind) {
+				if indexName == "" {
+					indexName = column.Column
+				}
+
+				idx, ok := indexes[indexName]
+				if !ok {
+					idx = &Index{Name: indexName, Unique: kind == "unique"}
+					indexes[indexName] = idx
+					indexNames = append(indexNames, indexName)
+				} else if idx.Unique != (kind == "unique") {
+					return nil, f.errorf(v.Pos(), "field %s: index %s declared both unique and non-unique", fieldName, indexName)
+				}
+				idx.Fields = append(idx.Fields, column)
+			}
+		}
+	}
+
+	// Fields of embedded structs are skipped while flattening, before others.
+	sort.SliceStable(skipped, func(i, j int) bool {
+		a, b := skipped[i].Pos, skipped[j].Pos
+		return a.Filename < b.Filename || a.Filename == b.Filename && a.Offset < b.Offset
+	})
+	if p.strict && len(skipped) > 0 {
+		return nil, skipped
+	}
+	for _, err := range skipped {
+		glog.Infof("Skipping %s\n", err)
 	}
+
+	if t.findField(func(f Field) bool { return f.IsPK }) == nil {
+		return nil, f.errorf(tspec.Pos(), "type %s has no primary key", name)
+	}
+
+	for _, indexName := range indexNames {
+		t.Indexes = append(t.Indexes, *indexes[indexName])
+	}
+	return t, nil
+}
+
+// skip returns the *Error reporting that the field of the given name, at pos,
+// is skipped, and why.
+func (f *File) skip(pos token.Pos, name string, format string, args ...interface{}) *Error {
+	msg := fmt.Sprintf("field %s: %s", name, fmt.Sprintf(format, args...))
+	return &Error{Pos: f.position(pos), Msg: msg}
+}
+
+// ignored reports whether a field is tagged `sqlgen:"-"`, to be left out.
+func ignored(tag string) bool {
+	return reflect.StructTag(tag).Get("sqlgen") == "-"
+}
+
+// typeString returns the name of typ in the source of the file, with types of
+// other packages qualified by their package name.
+func (f *File) typeString(typ types.Type) string {
+	return types.TypeString(typ, func(pkg *types.Package) string {
+		if pkg == f.pkg.Types {
+			return ""
+		}
+		return pkg.Name()
+	})
+}
+
+// relationTarget returns the struct type of the package of the file referred to
+// by a field of type typ, directly or through a pointer, and whether the field
+// refers to many of them, through a slice.
+func (f *File) relationTarget(typ types.Type) (string, bool, bool) {
+	var many bool
+	if slice, ok := types.Unalias(typ).(*types.Slice); ok {
+		typ, many = slice.Elem(), true
+	}
+	if ptr, ok := types.Unalias(typ).(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok || named.Obj().Pkg() != f.pkg.Types {
+		// Types of other packages are not tables.
+		return "", false, false
+	}
+	if _, ok := named.Underlying().(*types.Struct); !ok {
+		return "", false, false
+	}
+	return named.Obj().Name(), many, true
+}
+
+// importPath returns the path of the package to import for a field of type
+// typ, directly or through a pointer, or "" if it is predeclared or of the
+// package of the file.
+func (f *File) importPath(typ types.Type) string {
+	if ptr, ok := types.Unalias(typ).(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg() == f.pkg.Types {
+		return ""
+	}
+	return named.Obj().Pkg().Path()
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, item := range list {
+		if item == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// tagOptions holds the options in a `sqlgen:"..."` struct tag. Each option is
+// either a bare key or a key:value pair, and options are separated by commas.
+type tagOptions map[string][]string
+
+func parseTag(tag string) (tagOptions, error) {
+	opts := make(tagOptions)
+	value := reflect.StructTag(tag).Get("sqlgen")
+	if value == "" {
+		return opts, nil
+	}
+	for _, opt := range strings.Split(value, ",") {
+		opt = strings.TrimSpace(opt)
+		key, val := opt, ""
+		if i := strings.Index(opt, ":"); i >= 0 {
+			key, val = opt[:i], opt[i+1:]
+		}
+
+		switch key {
+		case "pk", "index", "unique", "version", "softdelete", "created", "updated", "prefix":
+		default:
+			return nil, fmt.Errorf("unknown sqlgen option %q", opt)
+		}
+		opts[key] = append(opts[key], val)
+	}
+	return opts, nil
+}
+
+func (opts tagOptions) has(key string) bool {
+	_, ok := opts[key]
+	return ok
+}
+
+func (opts tagOptions) values(key string) []string {
+	return opts[key]
 }
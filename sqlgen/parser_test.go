@@ -0,0 +1,272 @@
+package sqlgen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseType(t *testing.T) {
+	p := NewParser()
+	if err := p.AddDirectory("testdata"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ParseFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	typ, err := p.ParseType("TypeName")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedFields := []Field{
+		Field{Name: "srcName", Column: "srcname", IsPK: true, GoType: "int64", DBType: "BIGINT"},
+		Field{Name: "SrcName2", Column: "srcname2", IsPK: false, GoType: "string", DBType: "VARCHAR"},
+	}
+	if typ.Name != "TypeName" || typ.Table != "typename" || typ.Package != "foopackage" {
+		t.Fatalf("Unexpected type: %+v", typ)
+	}
+	if !reflect.DeepEqual(typ.Fields, expectedFields) {
+		t.Fatalf("Mismatch in fields:\nexpected %+v\nactual   %+v", expectedFields, typ.Fields)
+	}
+
+	expectedIndexes := []Index{
+		Index{Name: "srcname2", Fields: expectedFields[1:], Unique: false},
+	}
+	if !reflect.DeepEqual(typ.Indexes, expectedIndexes) {
+		t.Fatalf("Mismatch in indexes:\nexpected %+v\nactual   %+v", expectedIndexes, typ.Indexes)
+	}
+
+	expectedRelations := []Relation{
+		Relation{Field: "Parent", Target: "TypeName"},
+		Relation{Field: "Children", Target: "TypeName", Many: true},
+	}
+	if !reflect.DeepEqual(typ.Relations, expectedRelations) {
+		t.Fatalf("Mismatch in relations:\nexpected %+v\nactual   %+v", expectedRelations, typ.Relations)
+	}
+
+	if _, err := p.ParseType("Missing"); err == nil {
+		t.Fatal("Expected error for missing type")
+	}
+}
+
+func TestParseTypeTagOptions(t *testing.T) {
+	p := NewParser()
+	if err := p.AddDirectory("testdata"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ParseFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	p.SetTagOptions("pk", "prefix")
+	_, err := p.ParseType("TypeName")
+	if _, ok := err.(*Error); !ok {
+		t.Fatalf("Expected *Error, got %#v", err)
+	}
+	expectedError := "testdata/schema.go:5:2: field SrcName2: sqlgen option index is not supported"
+	if err.Error() != expectedError {
+		t.Fatalf("Mismatch in error:\nexpected %s\nactual   %s", expectedError, err)
+	}
+}
+
+func TestParseTypeErrors(t *testing.T) {
+	p := NewParser()
+	if err := p.AddDirectory("testdata/errors"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ParseFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedErrors := map[string]string{
+		"NotStruct":  "testdata/errors/schema.go:5:6: type NotStruct is not a struct",
+		"NoPK":       "testdata/errors/schema.go:7:6: type NoPK has no primary key",
+		"TwoPKs":     "testdata/errors/schema.go:13:2: field Code: primary key already declared by Id",
+		"BadTag":     "testdata/errors/schema.go:18:2: field Name: unknown sqlgen option \"colour\"",
+		"BadCreated": "testdata/errors/schema.go:23:2: field Created: created column must be a time.Time, not *time.Time",
+	}
+	for name, expectedError := range expectedErrors {
+		_, err := p.ParseType(name)
+		if _, ok := err.(*Error); !ok {
+			t.Fatalf("Expected *Error for %s, got %#v", name, err)
+		}
+		if err.Error() != expectedError {
+			t.Fatalf("Mismatch in error for %s:\nexpected %s\nactual   %s", name, expectedError, err)
+		}
+	}
+}
+
+func TestAddDirectoryMissing(t *testing.T) {
+	if err := NewParser().AddDirectory("testdata/missing"); err == nil {
+		t.Fatal("Expected error for missing directory")
+	}
+}
+
+func TestParseTypeStrict(t *testing.T) {
+	p := NewParser()
+	if err := p.AddDirectory("testdata/errors"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ParseFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	typ, err := p.ParseType("Skipped")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedRelations := []Relation{
+		Relation{Field: "Parent", Target: "Skipped"},
+		Relation{Field: "Children", Target: "Skipped", Many: true},
+	}
+	if len(typ.Fields) != 1 || typ.Fields[0].Name != "Id" || !reflect.DeepEqual(typ.Relations, expectedRelations) {
+		t.Fatalf("Unexpected type: %+v", typ)
+	}
+
+	p.SetStrict(true)
+	_, err = p.ParseType("Skipped")
+	expectedError := `testdata/errors/schema.go:28:2: field Count: unsupported type uint32
+testdata/errors/schema.go:30:3: field NoPK: embedded pointers are not supported
+testdata/errors/schema.go:31:7: field Time: embedded time.Time is not supported
+testdata/errors/schema.go:32:2: field NotStruct: NotStruct is not a struct
+testdata/errors/schema.go:33:2: field State: unsupported type Status
+testdata/errors/schema.go:34:2: field Parent: relation to Skipped is not stored`
+	if _, ok := err.(ErrorList); !ok {
+		t.Fatalf("Expected ErrorList, got %#v", err)
+	}
+	if err.Error() != expectedError {
+		t.Fatalf("Mismatch in error:\nexpected %s\nactual   %s", expectedError, err)
+	}
+}
+
+func TestParseTypeEmbedded(t *testing.T) {
+	p := NewParser()
+	if err := p.AddDirectory("testdata/embedded"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ParseFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	typ, err := p.ParseType("Account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFields := []Field{
+		Field{Name: "Id", Column: "id", IsPK: true, GoType: "int64", DBType: "BIGINT"},
+		Field{Name: "First", Column: "first", GoType: "string", DBType: "VARCHAR"},
+		Field{Name: "Last", Column: "last", GoType: "string", DBType: "VARCHAR"},
+		Field{Name: "CreatedAt", Column: "createdat", GoType: "time.Time", DBType: "TIMESTAMP"},
+		Field{Name: "CreatedBy", Column: "createdby", GoType: "string", DBType: "VARCHAR"},
+		Field{Name: "Email", Column: "contact_email", GoType: "string", DBType: "VARCHAR"},
+		Field{Name: "Phone", Column: "contact_phone", GoType: "string", DBType: "VARCHAR"},
+		Field{Name: "City", Column: "contact_addr_city", GoType: "string", DBType: "VARCHAR"},
+	}
+	if !reflect.DeepEqual(typ.Fields, expectedFields) {
+		t.Fatalf("Mismatch in fields:\nexpected %+v\nactual   %+v", expectedFields, typ.Fields)
+	}
+	if !reflect.DeepEqual(typ.Imports, []string{"time"}) {
+		t.Fatalf("Unexpected imports: %v", typ.Imports)
+	}
+
+	_, err = p.ParseType("Duplicate")
+	expectedError := "testdata/embedded/schema.go:29:2: field CreatedBy: column createdby already declared by CreatedBy"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("Mismatch in error:\nexpected %s\nactual   %v", expectedError, err)
+	}
+}
+
+func TestParseTypePackages(t *testing.T) {
+	p := NewParser()
+	p.AddPattern("./testdata/packages/...")
+	if err := p.ParseFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	const storePath = "github.com/anupcshan/sqlgen/sqlgen/testdata/packages"
+	typ, err := p.ParseType("Account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFields := []Field{
+		Field{Name: "Id", Column: "id", IsPK: true, GoType: "int64", DBType: "BIGINT"},
+		Field{Name: "Name", Column: "name", GoType: "string", DBType: "VARCHAR"},
+		Field{Name: "CreatedAt", Column: "createdat", GoType: "time.Time", DBType: "TIMESTAMP"},
+		Field{Name: "CreatedBy", Column: "createdby", GoType: "string", DBType: "VARCHAR"},
+	}
+	if typ.Package != "store" || typ.PkgPath != storePath {
+		t.Fatalf("Unexpected type: %+v", typ)
+	}
+	if !reflect.DeepEqual(typ.Fields, expectedFields) {
+		t.Fatalf("Mismatch in fields:\nexpected %+v\nactual   %+v", expectedFields, typ.Fields)
+	}
+
+	p.SetStrict(true)
+	_, err = p.ParseType("Account")
+	expectedError := "testdata/packages/models/models.go:8:2: field revision: unexported field of another package"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("Mismatch in error:\nexpected %s\nactual   %v", expectedError, err)
+	}
+
+	if _, err := p.ParseType("User"); err == nil {
+		t.Fatal("Expected error for type declared by several packages")
+	}
+	typ, err = p.ParseType(storePath + "/models.User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ.Package != "models" || typ.PkgPath != storePath+"/models" {
+		t.Fatalf("Unexpected type: %+v", typ)
+	}
+}
+
+func TestParseTypeUnparsedEmbedded(t *testing.T) {
+	// The package of the embedded struct is not among those parsed.
+	p := NewParser()
+	if err := p.AddDirectory("testdata/packages"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ParseFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	typ, err := p.ParseType("Account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFields := []Field{
+		Field{Name: "Id", Column: "id", IsPK: true, GoType: "int64", DBType: "BIGINT"},
+		Field{Name: "Name", Column: "name", GoType: "string", DBType: "VARCHAR"},
+		Field{Name: "CreatedAt", Column: "createdat", GoType: "time.Time", DBType: "TIMESTAMP"},
+		Field{Name: "CreatedBy", Column: "createdby", GoType: "string", DBType: "VARCHAR"},
+	}
+	if !reflect.DeepEqual(typ.Fields, expectedFields) {
+		t.Fatalf("Mismatch in fields:\nexpected %+v\nactual   %+v", expectedFields, typ.Fields)
+	}
+	if !reflect.DeepEqual(typ.Imports, []string{"time"}) {
+		t.Fatalf("Unexpected imports: %v", typ.Imports)
+	}
+}
+
+func TestTableTypes(t *testing.T) {
+	p := NewParser()
+	if err := p.AddDirectory("testdata/tables"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ParseFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	const pkgPath = "github.com/anupcshan/sqlgen/sqlgen/testdata/tables"
+	expectedNames := []string{pkgPath + ".User", pkgPath + ".Post", pkgPath + ".Comment"}
+	names := p.TableTypes()
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Fatalf("Mismatch in table types:\nexpected %v\nactual   %v", expectedNames, names)
+	}
+	for _, name := range names {
+		if _, err := p.ParseType(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
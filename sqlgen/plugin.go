@@ -0,0 +1,145 @@
+package sqlgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// GeneratedFile is a file emitted by a Plugin. Its name is relative to the
+// output directory.
+type GeneratedFile struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// Plugin generates additional files, such as mappers or fixtures, from the
+// parsed types.
+//
+// Plugins written in Go register themselves with RegisterPlugin from an init
+// function, and are enabled by importing their package in a custom main, which
+// parses the types and calls RunPlugins with Plugins(). Plugins in other
+// languages run as executables, with NewExecPlugin.
+type Plugin interface {
+	// Name identifies the plugin in flags and error messages.
+	Name() string
+	// Generate returns the files generated for types.
+	Generate(types []Type) ([]GeneratedFile, error)
+}
+
+var (
+	pluginsMu sync.Mutex
+	plugins   = make(map[string]Plugin)
+)
+
+// RegisterPlugin makes p available by name to Plugins and LookupPlugin. It
+// panics if p is nil or a plugin of the same name is already registered.
+func RegisterPlugin(p Plugin) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	if p == nil {
+		panic("sqlgen: RegisterPlugin plugin is nil")
+	}
+	if _, dup := plugins[p.Name()]; dup {
+		panic("sqlgen: RegisterPlugin called twice for plugin " + p.Name())
+	}
+	plugins[p.Name()] = p
+}
+
+// LookupPlugin returns the registered plugin of the given name.
+func LookupPlugin(name string) (Plugin, bool) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	p, ok := plugins[name]
+	return p, ok
+}
+
+// Plugins returns the registered plugins, sorted by name.
+func Plugins() []Plugin {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]Plugin, len(names))
+	for i, name := range names {
+		list[i] = plugins[name]
+	}
+	return list
+}
+
+// execPlugin is a plugin run as an executable.
+type execPlugin struct {
+	path string
+	args []string
+}
+
+// NewExecPlugin returns a plugin running the executable at path with args. The
+// executable reads the types as a JSON array of Type on its standard input, and
+// writes the generated files as a JSON array of GeneratedFile on its standard
+// output. Its standard error is passed through, and a non-zero exit status
+// fails the generation.
+func NewExecPlugin(path string, args ...string) Plugin {
+	return &execPlugin{path: path, args: args}
+}
+
+func (p *execPlugin) Name() string {
+	return filepath.Base(p.path)
+}
+
+func (p *execPlugin) Generate(types []Type) ([]GeneratedFile, error) {
+	model, err := json.Marshal(types)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(p.path, p.args...)
+	cmd.Stdin = bytes.NewReader(model)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	var files []GeneratedFile
+	if err := json.Unmarshal(stdout.Bytes(), &files); err != nil {
+		return nil, fmt.Errorf("decoding output: %s", err)
+	}
+	return files, nil
+}
+
+// RunPlugins runs each plugin on types, and writes the files they generate
+// into dir. Generated files may be in subdirectories of dir, but not outside
+// of it.
+func RunPlugins(plugins []Plugin, types []Type, dir string) error {
+	for _, p := range plugins {
+		files, err := p.Generate(types)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %s", p.Name(), err)
+		}
+		for _, file := range files {
+			name := filepath.Clean(file.Name)
+			if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+				return fmt.Errorf("plugin %s: file %s is outside of the output directory", p.Name(), file.Name)
+			}
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("plugin %s: %s", p.Name(), err)
+			}
+			if err := ioutil.WriteFile(path, []byte(file.Content), 0644); err != nil {
+				return fmt.Errorf("plugin %s: %s", p.Name(), err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package sqlgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tablesPlugin generates a file listing the tables of the types.
+type tablesPlugin struct {
+	name string
+}
+
+func (p tablesPlugin) Name() string {
+	return p.name
+}
+
+func (p tablesPlugin) Generate(types []Type) ([]GeneratedFile, error) {
+	var content string
+	for _, t := range types {
+		content += fmt.Sprintf("%s: %s\n", t.Name, t.Table)
+	}
+	return []GeneratedFile{{Name: "tables/" + p.name + ".txt", Content: content}}, nil
+}
+
+func TestRegisterPlugin(t *testing.T) {
+	p := tablesPlugin{name: "tables"}
+	RegisterPlugin(p)
+	defer func() {
+		pluginsMu.Lock()
+		delete(plugins, p.name)
+		pluginsMu.Unlock()
+	}()
+	if actual, ok := LookupPlugin("tables"); !ok || actual != Plugin(p) {
+		t.Fatalf("Registered plugin not found: %v", actual)
+	}
+	if registered := Plugins(); len(registered) != 1 || registered[0] != Plugin(p) {
+		t.Fatalf("Unexpected registered plugins: %v", registered)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected panic registering duplicate plugin")
+		}
+	}()
+	RegisterPlugin(p)
+}
+
+func TestRunPlugins(t *testing.T) {
+	dir := t.TempDir()
+	if err := RunPlugins([]Plugin{tablesPlugin{name: "first"}, tablesPlugin{name: "second"}}, []Type{_type}, dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"first", "second"} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, "tables", name+".txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "TypeName: tblName\n" {
+			t.Fatalf("Unexpected content of %s: %q", name, content)
+		}
+	}
+
+	if err := RunPlugins([]Plugin{tablesPlugin{name: "../../escape"}}, []Type{_type}, dir); err == nil {
+		t.Fatal("Expected error for file outside of the output directory")
+	}
+}
+
+// TestHelperPlugin is not a test, but the plugin run by TestExecPlugin, which
+// lists the fields of the types it reads on stdin.
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv("SQLGEN_HELPER_PLUGIN") != "1" {
+		return
+	}
+	var types []Type
+	if err := json.NewDecoder(os.Stdin).Decode(&types); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var files []GeneratedFile
+	for _, typ := range types {
+		var content string
+		for _, field := range typ.Fields {
+			content += fmt.Sprintf("%s %s\n", field.Column, field.DBType)
+		}
+		files = append(files, GeneratedFile{Name: typ.Name + ".txt", Content: content})
+	}
+	json.NewEncoder(os.Stdout).Encode(files)
+	os.Exit(0)
+}
+
+func TestExecPlugin(t *testing.T) {
+	t.Setenv("SQLGEN_HELPER_PLUGIN", "1")
+	p := NewExecPlugin(os.Args[0], "-test.run=^TestHelperPlugin$")
+
+	files, err := p.Generate([]Type{_type})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFiles := []GeneratedFile{
+		GeneratedFile{Name: "TypeName.txt", Content: "dbName BIGINT\ndbName2 VARCHAR\n"},
+	}
+	if len(files) != 1 || files[0] != expectedFiles[0] {
+		t.Fatalf("Mismatch in files:\nexpected %+v\nactual   %+v", expectedFiles, files)
+	}
+
+	t.Setenv("SQLGEN_HELPER_PLUGIN", "")
+	if _, err := p.Generate([]Type{_type}); err == nil {
+		t.Fatal("Expected error for plugin without output")
+	}
+}
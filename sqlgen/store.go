@@ -0,0 +1,54 @@
+package sqlgen
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// StoreGenerator generates a Store holding the queries of several types of a
+// package, whose transactions span the tables of all of them.
+type StoreGenerator struct {
+	buf       bytes.Buffer // Output buffer
+	templates *Templates   // Templates emitting the code; the defaults if nil
+	types     []*Type
+}
+
+func NewStoreGenerator(types []*Type) *StoreGenerator {
+	return &StoreGenerator{types: types}
+}
+
+// SetTemplates makes g emit code with templates instead of the defaults.
+func (g *StoreGenerator) SetTemplates(templates *Templates) {
+	g.templates = templates
+}
+
+// execute appends the output of the template name for the types.
+func (g *StoreGenerator) execute(name string) error {
+	return g.templates.execute(&g.buf, name, g.types)
+}
+
+// storeNames are the names declared by the Store, and the methods of Store and
+// StoreTx, which the types it holds cannot take for their accessors.
+var storeNames = map[string]bool{
+	"Store": true, "StoreTx": true, "NewStore": true,
+	"Warmup": true, "Close": true, "Transaction": true, "InTx": true,
+	"Commit": true, "Rollback": true, "Savepoint": true, "RollbackTo": true, "Release": true,
+}
+
+// Generate emits the Store, from the template "store". It fails on types whose
+// name is taken by the Store.
+func (g *StoreGenerator) Generate() error {
+	for _, t := range g.types {
+		if storeNames[t.Name] {
+			return fmt.Errorf("type %s: name clashes with a declaration of the Store", t.Name)
+		}
+	}
+	if err := g.execute("store"); err != nil {
+		return err
+	}
+	return formatSource(&g.buf)
+}
+
+func (g *StoreGenerator) Bytes() []byte {
+	return g.buf.Bytes()
+}
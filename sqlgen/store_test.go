@@ -0,0 +1,59 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStoreGenerator(t *testing.T) {
+	otherType := _type
+	otherType.Name = "OtherName"
+	otherType.Table = "otherTbl"
+	g := NewStoreGenerator([]*Type{&_type, &otherType})
+
+	expectedStoreDecl := `// Store holds the queries of TypeName, OtherName.
+type Store struct {
+	db *sql.DB
+	dialect sqlrt.Dialect
+	typeNameQuery *TypeNameQuery
+	otherNameQuery *OtherNameQuery
+}
+`
+	execute(t, g, "storeDeclaration")
+	if actualStoreDecl := g.buf.String(); !strings.HasPrefix(actualStoreDecl, expectedStoreDecl) {
+		t.Fatalf("Mismatch in store declaration str:\n%s\n", stringDelta(expectedStoreDecl, actualStoreDecl))
+	}
+
+	expectedAccessorsStr := `func (s *Store) TypeName() *TypeNameQuery {
+	return s.typeNameQuery
+}
+
+func (s *Store) OtherName() *OtherNameQuery {
+	return s.otherNameQuery
+}
+
+func (t *StoreTx) TypeName() *TypeNameExecutor {
+	return t.s.typeNameQuery.With(t.tx)
+}
+
+func (t *StoreTx) OtherName() *OtherNameExecutor {
+	return t.s.otherNameQuery.With(t.tx)
+}
+`
+	g.buf.Reset()
+	execute(t, g, "storeAccessors")
+	if actualAccessorsStr := g.buf.String(); actualAccessorsStr != expectedAccessorsStr {
+		t.Fatalf("Mismatch in store accessors str:\n%s\n", stringDelta(expectedAccessorsStr, actualAccessorsStr))
+	}
+}
+
+func TestStoreGeneratorNameClash(t *testing.T) {
+	for _, name := range []string{"Store", "StoreTx", "Transaction", "Commit", "Release"} {
+		otherType := _type
+		otherType.Name = name
+		err := NewStoreGenerator([]*Type{&_type, &otherType}).Generate()
+		if expected := "type " + name + ": name clashes with a declaration of the Store"; err == nil || err.Error() != expected {
+			t.Errorf("Mismatch in error:\nexpected %s\nactual   %v", expected, err)
+		}
+	}
+}
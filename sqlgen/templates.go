@@ -0,0 +1,179 @@
+package sqlgen
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// defaultTemplates are the templates built into sqlgen, parsed once and cloned
+// for use.
+var defaultTemplates = template.Must(template.New("").Funcs(templateFuncs).ParseFS(templateFiles, "templates/*.tmpl"))
+
+// Templates is a set of named templates emitting generated code. The templates
+// of a type receive its *Type, and Generator runs "file". The templates of a
+// Store receive its []*Type, and StoreGenerator runs "store". Both run the
+// empty templates "extra" and "storeExtra" last, and the header runs the empty
+// "extraImports", so that code can be added without copying other templates.
+//
+// Besides the model accessors, templates can call the functions used by the
+// defaults: finders, uniqueFinders, statements, updateArgs, needsClock,
+// fields, columns, placeholders, ptrs, keyMap, params, args, preds, nullType,
+// lower, lowerFirst, upper and list. TemplateFuncs returns them, for other
+// templates.
+type Templates struct {
+	tmpl *template.Template
+}
+
+// DefaultTemplates returns the templates built into sqlgen.
+func DefaultTemplates() *Templates {
+	return &Templates{tmpl: template.Must(defaultTemplates.Clone())}
+}
+
+// LoadTemplates returns the default templates, with those defined by the
+// *.tmpl files in dir added, or replacing the defaults of the same name.
+func LoadTemplates(dir string) (*Templates, error) {
+	t := DefaultTemplates()
+	if _, err := t.tmpl.ParseGlob(filepath.Join(dir, "*.tmpl")); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// execute writes the output of the template name for data to w. A nil
+// Templates runs the defaults.
+func (t *Templates) execute(w io.Writer, name string, data interface{}) error {
+	if t == nil {
+		// Executing the defaults themselves would prevent cloning them.
+		t = DefaultTemplates()
+	}
+	return t.tmpl.ExecuteTemplate(w, name, data)
+}
+
+// TemplateFuncs returns the functions which templates can call, as listed by
+// Templates.
+func TemplateFuncs() template.FuncMap {
+	funcs := make(template.FuncMap, len(templateFuncs))
+	for name, fn := range templateFuncs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+var templateFuncs = template.FuncMap{
+	"finders":       (*Type).finderIndexes,
+	"uniqueFinders": (*Type).uniqueIndexes,
+	"statements":    (*Type).statements,
+	"updateArgs":    (*Type).updateArgs,
+	"needsClock":    (*Type).needsClock,
+	"fields":        func(fields ...Field) []Field { return fields },
+	"columns":       columns,
+	"placeholders":  placeholders,
+	"ptrs":          ptrs,
+	"keyMap":        keyMap,
+	"params":        params,
+	"args":          args,
+	"preds":         preds,
+	"nullType":      func(srcType string) string { return numericSourceTypes[srcType] },
+	"lower":         strings.ToLower,
+	"lowerFirst":    func(s string) string { return strings.ToLower(s[:1]) + s[1:] },
+	"upper":         strings.ToUpper,
+	"list":          func(items ...string) []string { return items },
+}
+
+// numericSourceTypes are the source types which support SUM, MIN and MAX,
+// mapped to the nullable type scanning their aggregates.
+var numericSourceTypes = map[string]string{
+	"int64": "sql.NullInt64",
+	"int":   "sql.NullInt64",
+}
+
+// columns returns the comma-separated columns of fields.
+func columns(fields []Field) string {
+	var dbFieldNames bytes.Buffer
+	for i, field := range fields {
+		if i != 0 {
+			dbFieldNames.WriteString(",")
+		}
+		dbFieldNames.WriteString(field.Column)
+	}
+	return dbFieldNames.String()
+}
+
+// placeholders returns the comma-separated placeholders of fields, in order.
+func placeholders(fields []Field) string {
+	var placeholders bytes.Buffer
+	for i := range fields {
+		if i != 0 {
+			placeholders.WriteString(",")
+		}
+		placeholders.WriteString(fmt.Sprintf("$%d", i+1))
+	}
+	return placeholders.String()
+}
+
+// ptrs returns the list of pointers to fields of obj.
+func ptrs(fields []Field) string {
+	var srcFieldPtrs bytes.Buffer
+	for i, field := range fields {
+		if i != 0 {
+			srcFieldPtrs.WriteString(", ")
+		}
+		srcFieldPtrs.WriteString(fmt.Sprintf("&obj.%s", field.Name))
+	}
+	return srcFieldPtrs.String()
+}
+
+// keyMap returns a map literal from the columns of fields to their values,
+// read from variables named after the fields with the given prefix.
+func keyMap(fields []Field, prefix string) string {
+	var keys bytes.Buffer
+	for i, field := range fields {
+		if i != 0 {
+			keys.WriteString(", ")
+		}
+		keys.WriteString(fmt.Sprintf("%q: %s%s", field.Column, prefix, field.Name))
+	}
+	return fmt.Sprintf("map[string]interface{}{%s}", keys.String())
+}
+
+// params returns the parameter list of a finder on fields.
+func params(fields []Field) string {
+	var params bytes.Buffer
+	for i, field := range fields {
+		if i != 0 {
+			params.WriteString(", ")
+		}
+		params.WriteString(fmt.Sprintf("%s %s", field.Name, field.GoType))
+	}
+	return params.String()
+}
+
+// args returns the arguments passing on the parameters of a finder on fields.
+func args(fields []Field) string {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// preds returns the predicates matching the parameters of a finder on fields
+// of typeName.
+func preds(typeName string, fields []Field) string {
+	var preds bytes.Buffer
+	for i, field := range fields {
+		if i != 0 {
+			preds.WriteString(", ")
+		}
+		preds.WriteString(fmt.Sprintf("%sColumns.%s.Eq(%s)", typeName, field.Name, field.Name))
+	}
+	return preds.String()
+}
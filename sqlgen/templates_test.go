@@ -0,0 +1,44 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	templates, err := LoadTemplates("testdata/templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGenerator(&_type)
+	g.SetTemplates(templates)
+	if err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedExtraStr := `// Table returns the table holding TypeName.
+func (q *TypeNameQuery) Table() string {
+	return "tblName"
+}
+`
+	actualStr := string(g.Bytes())
+	if !strings.HasSuffix(actualStr, expectedExtraStr) {
+		t.Fatalf("Missing added template in file contents:\n%s\n", actualStr)
+	}
+	if !strings.Contains(actualStr, `var ErrTypeNameNotFound = sqlrt.NotFound("TypeName")`) {
+		t.Fatalf("Missing overridden template in file contents:\n%s\n", actualStr)
+	}
+
+	// The defaults are left unchanged.
+	g = NewGenerator(&_type)
+	execute(t, g, "errors")
+	if expectedErrorsStr := "var ErrTypeNameNotFound = sqlrt.NotFound(\"tblName\")\n"; g.buf.String() != expectedErrorsStr {
+		t.Fatalf("Mismatch in errors str:\n%s\n", stringDelta(expectedErrorsStr, g.buf.String()))
+	}
+}
+
+func TestLoadTemplatesMissingDirectory(t *testing.T) {
+	if _, err := LoadTemplates("testdata/missing"); err == nil {
+		t.Fatal("Expected error for missing templates")
+	}
+}
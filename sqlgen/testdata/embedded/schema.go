@@ -0,0 +1,30 @@
+package embedded
+
+import "time"
+
+type Audit struct {
+	CreatedAt time.Time
+	CreatedBy string
+}
+
+type Address struct {
+	City string
+}
+
+type Contact struct {
+	Email, Phone string
+	Address      `sqlgen:"prefix:addr_"`
+}
+
+type Account struct {
+	Id          int64
+	First, Last string
+	Audit
+	Contact `sqlgen:"prefix:contact_"`
+}
+
+type Duplicate struct {
+	Id int64
+	Audit
+	CreatedBy string
+}
@@ -0,0 +1,38 @@
+package errors
+
+import "time"
+
+type NotStruct int
+
+type NoPK struct {
+	Name string
+}
+
+type TwoPKs struct {
+	Id   int64
+	Code string `sqlgen:"pk"`
+}
+
+type BadTag struct {
+	Id   int64
+	Name string `sqlgen:"colour"`
+}
+
+type BadCreated struct {
+	Id      int64
+	Created *time.Time `sqlgen:"created"`
+}
+
+type Skipped struct {
+	Id      int64
+	Count   uint32
+	Ignored uint32 `sqlgen:"-"`
+	*NoPK
+	time.Time
+	NotStruct
+	State    Status
+	Parent   *Skipped
+	Children []*Skipped `sqlgen:"-"`
+}
+
+type Status string
@@ -2,64 +2,102 @@
 
 package foopackage
 
+import "context"
 import "database/sql"
 
+import "github.com/anupcshan/sqlgen/sqlrt"
+
 type TypeNameQuery struct {
-	db         *sql.DB
-	create     *sql.Stmt
-	bysrcName  *sql.Stmt
-	bySrcName2 *sql.Stmt
-	delete     *sql.Stmt
-	update     *sql.Stmt
+	TypeNameExecutor
+	db        *sql.DB
+	dialect   sqlrt.Dialect
+	logger    sqlrt.Logger
+	create    *sqlrt.Stmt
+	bysrcName *sqlrt.Stmt
+	delete    *sqlrt.Stmt
+	update    *sqlrt.Stmt
 }
 
 type TypeNameQueryTx struct {
+	TypeNameExecutor
 	tx *sql.Tx
-	q  *TypeNameQuery
 }
 
-func (q *TypeNameQuery) Validate() error {
-	if stmt, err := q.db.Prepare("INSERT INTO tblName(dbName,dbName2) VALUES($1,$2)"); err != nil {
-		return err
-	} else {
-		q.create = stmt
-	}
+// TypeNameExecutor runs the operations of TypeNameQuery on a sqlrt.DBTX.
+type TypeNameExecutor struct {
+	exec sqlrt.DBTX
+	q    *TypeNameQuery
+}
 
-	if stmt, err := q.db.Prepare("SELECT dbName,dbName2 FROM tblName WHERE dbName=$1"); err != nil {
-		return err
-	} else {
-		q.bysrcName = stmt
-	}
+var ErrTypeNameNotFound = sqlrt.NotFound("tblName")
 
-	if stmt, err := q.db.Prepare("SELECT dbName,dbName2 FROM tblName WHERE dbName2=$1"); err != nil {
-		return err
-	} else {
-		q.bySrcName2 = stmt
-	}
+func newTypeNameQuery(db *sql.DB, o sqlrt.Options) *TypeNameQuery {
+	q := &TypeNameQuery{db: db, dialect: o.Dialect, logger: o.Logger}
+	q.init()
+	return q
+}
 
-	if stmt, err := q.db.Prepare("UPDATE tblName SET (dbName2)=($2) WHERE dbName=$1"); err != nil {
-		return err
-	} else {
-		q.update = stmt
+// NewTypeNameQuery returns the queries of TypeName on db, configured by opts.
+// Statements are prepared on first use, unless sqlrt.WithWarmup is given.
+func NewTypeNameQuery(db *sql.DB, opts ...sqlrt.Option) (*TypeNameQuery, error) {
+	o := sqlrt.NewOptions(opts...)
+	q := newTypeNameQuery(db, o)
+	if !o.Warmup {
+		return q, nil
 	}
-
-	if stmt, err := q.db.Prepare("DELETE FROM tblName WHERE dbName=$1"); err != nil {
-		return err
-	} else {
-		q.delete = stmt
+	if err := q.Warmup(context.Background()); err != nil {
+		q.Close()
+		return nil, err
 	}
+	return q, nil
+}
 
-	return nil
+// init sets up the statements of q, which are prepared on first use.
+func (q *TypeNameQuery) init() {
+	q.TypeNameExecutor = TypeNameExecutor{exec: q.db, q: q}
+	q.create = sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "INSERT INTO tblName(dbName,dbName2) VALUES($1,$2)"))
+	q.bysrcName = sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "SELECT dbName,dbName2 FROM tblName WHERE dbName=$1"))
+	q.update = sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "UPDATE tblName SET dbName2=$1 WHERE dbName=$2"))
+	q.delete = sqlrt.NewStmt(q.db, q.logger, sqlrt.Rebind(q.dialect, "DELETE FROM tblName WHERE dbName=$1"))
+}
+
+// Validate prepares every statement of q, checking them against the schema.
+func (q *TypeNameQuery) Validate() error {
+	return q.Warmup(context.Background())
+}
+
+// Warmup prepares every statement of q ahead of its first use.
+func (q *TypeNameQuery) Warmup(ctx context.Context) error {
+	return sqlrt.PrepareAll(ctx, q.create, q.bysrcName, q.update, q.delete)
+}
+
+// Close releases the prepared statements of q.
+func (q *TypeNameQuery) Close() error {
+	return sqlrt.CloseAll(q.create, q.bysrcName, q.update, q.delete)
 }
 
 func (q *TypeNameQuery) Transaction() (*TypeNameQueryTx, error) {
 	if tx, err := q.db.Begin(); err != nil {
 		return nil, err
 	} else {
-		return &TypeNameQueryTx{tx: tx, q: q}, nil
+		return &TypeNameQueryTx{TypeNameExecutor: TypeNameExecutor{exec: tx, q: q}, tx: tx}, nil
 	}
 }
 
+// InTx runs fn in a transaction, committed if fn succeeds and rolled back
+// otherwise. See sqlrt.InTx.
+func (q *TypeNameQuery) InTx(ctx context.Context, opts *sqlrt.TxOptions, fn func(tx *TypeNameQueryTx) error) error {
+	return sqlrt.InTx(ctx, q.db, q.dialect, opts, func(tx *sql.Tx) error {
+		return fn(&TypeNameQueryTx{TypeNameExecutor: TypeNameExecutor{exec: tx, q: q}, tx: tx})
+	})
+}
+
+// With returns the operations of TypeNameQuery running on db, such as a transaction
+// or connection owned by the caller.
+func (q *TypeNameQuery) With(db sqlrt.DBTX) *TypeNameExecutor {
+	return &TypeNameExecutor{exec: db, q: q}
+}
+
 func (t *TypeNameQueryTx) Commit() error {
 	return t.tx.Commit()
 }
@@ -68,29 +106,260 @@ func (t *TypeNameQueryTx) Rollback() error {
 	return t.tx.Rollback()
 }
 
-func (t *TypeNameQueryTx) Create(obj *TypeName) error {
-	stmt := t.tx.Stmt(t.q.create)
-	if _, err := stmt.Exec(&obj.srcName, &obj.SrcName2); err != nil {
-		return err
+func (t *TypeNameQueryTx) Savepoint(name string) error {
+	return sqlrt.Savepoint(context.Background(), t.tx, name)
+}
+
+func (t *TypeNameQueryTx) RollbackTo(name string) error {
+	return sqlrt.RollbackTo(context.Background(), t.tx, name)
+}
+
+func (t *TypeNameQueryTx) Release(name string) error {
+	return sqlrt.Release(context.Background(), t.tx, name)
+}
+
+// InTx runs fn within a savepoint of the transaction, released if fn succeeds
+// and rolled back to otherwise. opts are ignored, as the enclosing transaction
+// determines the isolation and cannot be retried from within.
+func (t *TypeNameQueryTx) InTx(ctx context.Context, opts *sqlrt.TxOptions, fn func(tx *TypeNameQueryTx) error) error {
+	return sqlrt.InSavepoint(ctx, t.tx, func() error {
+		return fn(t)
+	})
+}
+
+func (e *TypeNameExecutor) Create(ctx context.Context, obj *TypeName) error {
+	if hook, ok := interface{}(obj).(sqlrt.BeforeCreater); ok {
+		if err := hook.BeforeCreate(); err != nil {
+			return err
+		}
+	}
+	if _, err := e.q.create.ExecContext(ctx, e.exec, &obj.srcName, &obj.SrcName2); err != nil {
+		return sqlrt.WrapError(e.q.dialect, "tblName", map[string]interface{}{"dbName": obj.srcName}, err)
 	} else {
 		return nil
 	}
 }
 
-func (t *TypeNameQueryTx) Update(obj *TypeName) error {
-	stmt := t.tx.Stmt(t.q.update)
-	if _, err := stmt.Exec(&obj.srcName, &obj.SrcName2); err != nil {
-		return err
+func (e *TypeNameExecutor) Update(ctx context.Context, obj *TypeName) error {
+	if hook, ok := interface{}(obj).(sqlrt.BeforeUpdater); ok {
+		if err := hook.BeforeUpdate(); err != nil {
+			return err
+		}
+	}
+	if _, err := e.q.update.ExecContext(ctx, e.exec, &obj.SrcName2, &obj.srcName); err != nil {
+		return sqlrt.WrapError(e.q.dialect, "tblName", map[string]interface{}{"dbName": obj.srcName}, err)
 	} else {
 		return nil
 	}
 }
 
-func (t *TypeNameQueryTx) Delete(obj *TypeName) error {
-	stmt := t.tx.Stmt(t.q.delete)
-	if _, err := stmt.Exec(&obj.srcName); err != nil {
-		return err
+func (e *TypeNameExecutor) Delete(ctx context.Context, obj *TypeName) error {
+	if hook, ok := interface{}(obj).(sqlrt.BeforeDeleter); ok {
+		if err := hook.BeforeDelete(); err != nil {
+			return err
+		}
+	}
+	if _, err := e.q.delete.ExecContext(ctx, e.exec, &obj.srcName); err != nil {
+		return sqlrt.WrapError(e.q.dialect, "tblName", map[string]interface{}{"dbName": obj.srcName}, err)
 	} else {
 		return nil
 	}
 }
+
+var TypeNameColumns = struct {
+	srcName  sqlrt.Column[int64]
+	SrcName2 sqlrt.Column[string]
+}{
+	srcName:  sqlrt.Column[int64]{Name: "dbName"},
+	SrcName2: sqlrt.Column[string]{Name: "dbName2"},
+}
+
+type TypeNameSelect struct {
+	db      sqlrt.Queryer
+	dialect sqlrt.Dialect
+	logger  sqlrt.Logger
+	sel     sqlrt.Select
+}
+
+func newTypeNameSelect(db sqlrt.Queryer, dialect sqlrt.Dialect, logger sqlrt.Logger) *TypeNameSelect {
+	if dialect == nil {
+		dialect = sqlrt.Postgres
+	}
+	sel := sqlrt.Select{Table: "tblName", Columns: []string{"dbName", "dbName2"}}
+	return &TypeNameSelect{db: db, dialect: dialect, logger: logger, sel: sel}
+}
+
+func (e *TypeNameExecutor) Select() *TypeNameSelect {
+	return newTypeNameSelect(e.exec, e.q.dialect, e.q.logger)
+}
+
+func (s *TypeNameSelect) Where(preds ...sqlrt.Predicate) *TypeNameSelect {
+	s.sel.Where = append(s.sel.Where, preds...)
+	return s
+}
+
+func (s *TypeNameSelect) OrderBy(orders ...sqlrt.Order) *TypeNameSelect {
+	s.sel.OrderBy = append(s.sel.OrderBy, orders...)
+	return s
+}
+
+func (s *TypeNameSelect) Limit(n int) *TypeNameSelect {
+	s.sel.Limit = n
+	return s
+}
+
+func (s *TypeNameSelect) Offset(n int) *TypeNameSelect {
+	s.sel.Offset = n
+	return s
+}
+
+func (s *TypeNameSelect) All(ctx context.Context) ([]*TypeName, error) {
+	query, args := s.sel.Build(s.dialect)
+	sqlrt.Log(ctx, s.logger, query, args)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objs []*TypeName
+	for rows.Next() {
+		obj := new(TypeName)
+		if err := rows.Scan(&obj.srcName, &obj.SrcName2); err != nil {
+			return nil, err
+		}
+		if hook, ok := interface{}(obj).(sqlrt.AfterLoader); ok {
+			if err := hook.AfterLoad(); err != nil {
+				return nil, err
+			}
+		}
+		objs = append(objs, obj)
+	}
+	return objs, rows.Err()
+}
+
+func (s *TypeNameSelect) Page(ctx context.Context, page sqlrt.Page) ([]*TypeName, string, error) {
+	key := new(TypeName)
+	keyPtr := func(column string) interface{} {
+		return ptrToTypeNameColumn(key, column)
+	}
+	if err := page.Apply(&s.sel, "dbName", keyPtr); err != nil {
+		return nil, "", err
+	}
+
+	objs, err := s.All(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	lastPtr := func(column string) interface{} {
+		return ptrToTypeNameColumn(objs[len(objs)-1], column)
+	}
+	next, err := page.NextToken("dbName", len(objs), lastPtr)
+	return objs, next, err
+}
+
+func (s *TypeNameSelect) First(ctx context.Context) (*TypeName, error) {
+	objs, err := s.Limit(1).All(ctx)
+	if err != nil {
+		return nil, err
+	} else if len(objs) == 0 {
+		return nil, ErrTypeNameNotFound
+	}
+	return objs[0], nil
+}
+
+func ptrToTypeNameColumn(obj *TypeName, column string) interface{} {
+	switch column {
+	case "dbName":
+		return &obj.srcName
+	case "dbName2":
+		return &obj.SrcName2
+	}
+	return nil
+}
+
+func (e *TypeNameExecutor) BysrcName(ctx context.Context, srcName int64) (*TypeName, error) {
+	row := e.q.bysrcName.QueryRowContext(ctx, e.exec, srcName)
+	obj := new(TypeName)
+	if err := row.Scan(&obj.srcName, &obj.SrcName2); err == sql.ErrNoRows {
+		return nil, &sqlrt.Error{Table: "tblName", Key: map[string]interface{}{"dbName": srcName}, Err: ErrTypeNameNotFound}
+	} else if err != nil {
+		return nil, err
+	}
+	if hook, ok := interface{}(obj).(sqlrt.AfterLoader); ok {
+		if err := hook.AfterLoad(); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+func (e *TypeNameExecutor) BySrcName2(ctx context.Context, SrcName2 string, page sqlrt.Page) ([]*TypeName, string, error) {
+	return e.Select().Where(TypeNameColumns.SrcName2.Eq(SrcName2)).Page(ctx, page)
+}
+
+func (s *TypeNameSelect) aggregate(ctx context.Context, expr string, dest interface{}) error {
+	query, args := s.sel.Aggregate(expr).Build(s.dialect)
+	sqlrt.Log(ctx, s.logger, query, args)
+	return s.db.QueryRowContext(ctx, query, args...).Scan(dest)
+}
+
+func (s *TypeNameSelect) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.aggregate(ctx, "COUNT(*)", &count)
+	return count, err
+}
+
+func (s *TypeNameSelect) Exists(ctx context.Context) (bool, error) {
+	var exists bool
+	query, args := s.sel.BuildExists(s.dialect)
+	sqlrt.Log(ctx, s.logger, query, args)
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&exists)
+	return exists, err
+}
+
+func (s *TypeNameSelect) SumsrcName(ctx context.Context) (int64, error) {
+	var value sql.NullInt64
+	if err := s.aggregate(ctx, "SUM(dbName)", &value); err != nil {
+		return 0, err
+	}
+	return int64(value.Int64), nil
+}
+
+func (s *TypeNameSelect) MinsrcName(ctx context.Context) (int64, error) {
+	var value sql.NullInt64
+	if err := s.aggregate(ctx, "MIN(dbName)", &value); err != nil {
+		return 0, err
+	}
+	if !value.Valid {
+		return 0, sql.ErrNoRows
+	}
+	return int64(value.Int64), nil
+}
+
+func (s *TypeNameSelect) MaxsrcName(ctx context.Context) (int64, error) {
+	var value sql.NullInt64
+	if err := s.aggregate(ctx, "MAX(dbName)", &value); err != nil {
+		return 0, err
+	}
+	if !value.Valid {
+		return 0, sql.ErrNoRows
+	}
+	return int64(value.Int64), nil
+}
+
+func (e *TypeNameExecutor) CountBysrcName(ctx context.Context, srcName int64) (int64, error) {
+	return e.Select().Where(TypeNameColumns.srcName.Eq(srcName)).Count(ctx)
+}
+
+func (e *TypeNameExecutor) ExistsBysrcName(ctx context.Context, srcName int64) (bool, error) {
+	return e.Select().Where(TypeNameColumns.srcName.Eq(srcName)).Exists(ctx)
+}
+
+func (e *TypeNameExecutor) CountBySrcName2(ctx context.Context, SrcName2 string) (int64, error) {
+	return e.Select().Where(TypeNameColumns.SrcName2.Eq(SrcName2)).Count(ctx)
+}
+
+func (e *TypeNameExecutor) ExistsBySrcName2(ctx context.Context, SrcName2 string) (bool, error) {
+	return e.Select().Where(TypeNameColumns.SrcName2.Eq(SrcName2)).Exists(ctx)
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type Audit struct {
+	CreatedAt time.Time
+	CreatedBy string
+	revision  int64
+}
+
+type User struct {
+	Id   int64
+	Name string
+}
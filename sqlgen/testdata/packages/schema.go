@@ -0,0 +1,13 @@
+package store
+
+import "github.com/anupcshan/sqlgen/sqlgen/testdata/packages/models"
+
+type Account struct {
+	Id   int64
+	Name string
+	models.Audit
+}
+
+type User struct {
+	Id int64
+}
@@ -1,6 +1,8 @@
 package foopackage
 
 type TypeName struct {
-	srcName  int64
-	SrcName2 string
+	srcName  int64  `sqlgen:"pk"`
+	SrcName2 string `sqlgen:"index"`
+	Parent   *TypeName
+	Children []*TypeName
 }
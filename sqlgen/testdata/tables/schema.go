@@ -0,0 +1,32 @@
+package tables
+
+// User is a user of the service.
+//
+//sqlgen:table
+type User struct {
+	Id   int64
+	Name string
+}
+
+// Session is not stored.
+type Session struct {
+	Token string
+}
+
+type (
+	//sqlgen:table
+	Post struct {
+		Id    int64
+		Title string
+	}
+
+	Draft struct {
+		Id int64
+	}
+)
+
+//sqlgen:table
+type Comment struct {
+	Id   int64
+	Text string
+}
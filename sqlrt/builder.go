@@ -0,0 +1,114 @@
+package sqlrt
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+)
+
+// Queryer is implemented by *sql.DB and *sql.Tx.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// DBTX is implemented by *sql.DB, *sql.Tx and *sql.Conn.
+type DBTX interface {
+	Queryer
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// Scanner is implemented by *sql.Row and *sql.Rows.
+type Scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Builder accumulates an SQL statement and its bind arguments.
+type Builder struct {
+	dialect Dialect
+	buf     bytes.Buffer
+	args    []interface{}
+}
+
+func NewBuilder(dialect Dialect) *Builder {
+	return &Builder{dialect: dialect}
+}
+
+func (b *Builder) WriteString(s string) *Builder {
+	b.buf.WriteString(s)
+	return b
+}
+
+// Arg appends a placeholder for v to the statement.
+func (b *Builder) Arg(v interface{}) *Builder {
+	b.args = append(b.args, v)
+	b.buf.WriteString(b.dialect.Placeholder(len(b.args)))
+	return b
+}
+
+func (b *Builder) String() string {
+	return b.buf.String()
+}
+
+func (b *Builder) Args() []interface{} {
+	return b.args
+}
+
+// Select describes a SELECT statement over a single table.
+type Select struct {
+	Table   string
+	Columns []string
+	Scope   []Predicate // Conditions applied to every query, such as excluding deleted rows
+	Where   []Predicate
+	OrderBy []Order
+	Limit   int // No limit if 0
+	Offset  int
+}
+
+// Build renders the statement in the given dialect.
+func (s *Select) Build(dialect Dialect) (string, []interface{}) {
+	b := NewBuilder(dialect)
+	b.WriteString("SELECT ")
+	for i, column := range s.Columns {
+		if i != 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(column)
+	}
+	b.WriteString(" FROM ").WriteString(s.Table)
+
+	if where := append(append([]Predicate{}, s.Scope...), s.Where...); len(where) != 0 {
+		b.WriteString(" WHERE ")
+		And(where...).WriteSQL(b)
+	}
+
+	for i, order := range s.OrderBy {
+		if i == 0 {
+			b.WriteString(" ORDER BY ")
+		} else {
+			b.WriteString(",")
+		}
+		order.WriteSQL(b)
+	}
+
+	if s.Limit != 0 {
+		b.WriteString(" LIMIT ").Arg(s.Limit)
+	}
+	if s.Offset != 0 {
+		b.WriteString(" OFFSET ").Arg(s.Offset)
+	}
+	return b.String(), b.Args()
+}
+
+// Aggregate returns a copy of s selecting the single expression expr, such as
+// COUNT(*), over the matching rows. Ordering and paging are dropped.
+func (s *Select) Aggregate(expr string) *Select {
+	return &Select{Table: s.Table, Columns: []string{expr}, Scope: s.Scope, Where: s.Where}
+}
+
+// BuildExists renders a statement returning whether any row matches s.
+func (s *Select) BuildExists(dialect Dialect) (string, []interface{}) {
+	query, args := s.Aggregate("1").Build(dialect)
+	return "SELECT EXISTS (" + query + ")", args
+}
@@ -0,0 +1,79 @@
+package sqlrt
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSelectBuild(t *testing.T) {
+	bar := Column[string]{Name: "bar"}
+	id := Column[int64]{Name: "id"}
+
+	sel := Select{
+		Table:   "foo",
+		Columns: []string{"id", "bar"},
+		Where:   []Predicate{bar.Eq("x"), Or(id.Gt(3), id.In(1, 2))},
+		OrderBy: []Order{id.Desc(), bar.Asc()},
+		Limit:   10,
+	}
+
+	expectedQuery := "SELECT id,bar FROM foo WHERE (bar=$1) AND ((id>$2) OR (id IN ($3,$4))) ORDER BY id DESC,bar ASC LIMIT $5"
+	expectedArgs := []interface{}{"x", int64(3), int64(1), int64(2), 10}
+	query, args := sel.Build(Postgres)
+	if query != expectedQuery {
+		t.Fatalf("Mismatch in query:\n-- %s\n++ %s\n", expectedQuery, query)
+	}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Fatalf("Mismatch in args: expected %v, got %v\n", expectedArgs, args)
+	}
+
+	query, _ = sel.Build(MySQL)
+	expectedQuery = "SELECT id,bar FROM foo WHERE (bar=?) AND ((id>?) OR (id IN (?,?))) ORDER BY id DESC,bar ASC LIMIT ?"
+	if query != expectedQuery {
+		t.Fatalf("Mismatch in query:\n-- %s\n++ %s\n", expectedQuery, query)
+	}
+}
+
+func TestSelectBuildNoWhere(t *testing.T) {
+	sel := Select{Table: "foo", Columns: []string{"id"}}
+	if query, args := sel.Build(Postgres); query != "SELECT id FROM foo" || len(args) != 0 {
+		t.Fatalf("Unexpected query %q with args %v\n", query, args)
+	}
+}
+
+func TestSelectAggregate(t *testing.T) {
+	id := Column[int64]{Name: "id"}
+	sel := Select{
+		Table:   "foo",
+		Columns: []string{"id", "bar"},
+		Where:   []Predicate{id.Gt(3)},
+		OrderBy: []Order{id.Desc()},
+		Limit:   10,
+	}
+
+	if query, args := sel.Aggregate("COUNT(*)").Build(Postgres); query != "SELECT COUNT(*) FROM foo WHERE id>$1" || len(args) != 1 {
+		t.Fatalf("Unexpected count query %q with args %v\n", query, args)
+	}
+	if query, args := sel.BuildExists(Postgres); query != "SELECT EXISTS (SELECT 1 FROM foo WHERE id>$1)" || len(args) != 1 {
+		t.Fatalf("Unexpected exists query %q with args %v\n", query, args)
+	}
+}
+
+func TestSelectScope(t *testing.T) {
+	deleted := Column[*time.Time]{Name: "deleted"}
+	id := Column[int64]{Name: "id"}
+	sel := Select{
+		Table:   "foo",
+		Columns: []string{"id"},
+		Scope:   []Predicate{deleted.IsNull()},
+		Where:   []Predicate{id.Gt(3)},
+	}
+
+	if query, _ := sel.Build(Postgres); query != "SELECT id FROM foo WHERE (deleted IS NULL) AND (id>$1)" {
+		t.Fatalf("Unexpected scoped query %q\n", query)
+	}
+	if query, _ := sel.Aggregate("COUNT(*)").Build(Postgres); query != "SELECT COUNT(*) FROM foo WHERE (deleted IS NULL) AND (id>$1)" {
+		t.Fatalf("Unexpected scoped count query %q\n", query)
+	}
+}
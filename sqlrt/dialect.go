@@ -0,0 +1,143 @@
+// Package sqlrt contains the runtime support used by code generated by sqlgen.
+package sqlrt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect captures the differences in SQL syntax between databases.
+type Dialect interface {
+	// Name of the database, for diagnostics.
+	Name() string
+
+	// Placeholder returns the bind parameter for the n-th (1-based) argument.
+	Placeholder(n int) string
+
+	// Classify returns ErrDuplicate or ErrForeignKey if err reports a
+	// violation of the corresponding constraint, and nil otherwise.
+	Classify(err error) error
+
+	// Retryable reports whether err aborted a transaction because of a
+	// serialization failure or deadlock, so that running it again may succeed.
+	Retryable(err error) bool
+}
+
+// Rebind returns query with its $N placeholders rewritten for dialect, which
+// is Postgres if nil. The placeholders must be numbered in order of
+// appearance, as dialects such as MySQL bind them by position.
+func Rebind(dialect Dialect, query string) string {
+	if dialect == nil || dialect == Postgres {
+		return query
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(query); i++ {
+		j := i + 1
+		for j < len(query) && '0' <= query[j] && query[j] <= '9' {
+			j++
+		}
+		if query[i] != '$' || j == i+1 {
+			buf.WriteByte(query[i])
+			continue
+		}
+		n, _ := strconv.Atoi(query[i+1 : j])
+		buf.WriteString(dialect.Placeholder(n))
+		i = j - 1
+	}
+	return buf.String()
+}
+
+var (
+	Postgres Dialect = postgres{}
+	MySQL    Dialect = mysql{}
+	SQLite   Dialect = sqlite{}
+)
+
+type postgres struct{}
+
+func (postgres) Name() string { return "postgres" }
+
+func (postgres) Placeholder(n int) string { return fmt.Sprintf("$%d", n) }
+
+func (postgres) Classify(err error) error {
+	state := sqlState(err)
+	if state == "" {
+		// lib/pq does not expose the code through an interface, but includes
+		// the constraint in the message.
+		msg := err.Error()
+		switch {
+		case strings.Contains(msg, "violates unique constraint"):
+			state = "23505"
+		case strings.Contains(msg, "violates foreign key constraint"):
+			state = "23503"
+		}
+	}
+
+	switch state {
+	case "23505":
+		return ErrDuplicate
+	case "23503":
+		return ErrForeignKey
+	}
+	return nil
+}
+
+func (postgres) Retryable(err error) bool {
+	switch sqlState(err) {
+	case "40001", "40P01":
+		return true
+	case "":
+		msg := err.Error()
+		return strings.Contains(msg, "could not serialize access") || strings.Contains(msg, "deadlock detected")
+	}
+	return false
+}
+
+type mysql struct{}
+
+func (mysql) Name() string { return "mysql" }
+
+func (mysql) Placeholder(n int) string { return "?" }
+
+func (mysql) Classify(err error) error {
+	msg := err.Error()
+	switch {
+	case strings.HasPrefix(msg, "Error 1062"):
+		return ErrDuplicate
+	case strings.HasPrefix(msg, "Error 1451"), strings.HasPrefix(msg, "Error 1452"):
+		return ErrForeignKey
+	}
+	return nil
+}
+
+func (mysql) Retryable(err error) bool {
+	// Deadlock found, and lock wait timeout exceeded.
+	msg := err.Error()
+	return strings.HasPrefix(msg, "Error 1213") || strings.HasPrefix(msg, "Error 1205")
+}
+
+type sqlite struct{}
+
+func (sqlite) Name() string { return "sqlite" }
+
+func (sqlite) Placeholder(n int) string { return fmt.Sprintf("?%d", n) }
+
+func (sqlite) Classify(err error) error {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"):
+		return ErrDuplicate
+	case strings.Contains(msg, "FOREIGN KEY constraint failed"):
+		return ErrForeignKey
+	}
+	return nil
+}
+
+func (sqlite) Retryable(err error) bool {
+	// SQLite serializes writers, which fail with SQLITE_BUSY while another
+	// transaction holds the lock.
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "database table is locked")
+}
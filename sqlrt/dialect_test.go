@@ -0,0 +1,21 @@
+package sqlrt
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	const query = "UPDATE foo SET bar=$1,version=version+1 WHERE id=$2 AND version=$3"
+	cases := []struct {
+		dialect  Dialect
+		expected string
+	}{
+		{nil, query},
+		{Postgres, query},
+		{MySQL, "UPDATE foo SET bar=?,version=version+1 WHERE id=? AND version=?"},
+		{SQLite, "UPDATE foo SET bar=?1,version=version+1 WHERE id=?2 AND version=?3"},
+	}
+	for _, c := range cases {
+		if actual := Rebind(c.dialect, query); actual != c.expected {
+			t.Errorf("Rebind(%v) = %q, expected %q", c.dialect, actual, c.expected)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package sqlrt
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrDuplicate  = errors.New("sqlrt: duplicate key")
+	ErrForeignKey = errors.New("sqlrt: foreign key violation")
+
+	// ErrStaleObject is returned when updating a row whose version has
+	// changed since it was read.
+	ErrStaleObject = errors.New("sqlrt: stale object")
+)
+
+// NotFound returns the sentinel error for a missing row of table. It wraps
+// sql.ErrNoRows.
+func NotFound(table string) error {
+	return fmt.Errorf("sqlrt: %s not found: %w", table, sql.ErrNoRows)
+}
+
+// Error reports a failed operation on the row of Table identified by Key.
+type Error struct {
+	Table string
+	Key   map[string]interface{}
+	Err   error // Sentinel describing the failure, such as ErrDuplicate.
+	Cause error // Error returned by the driver, if any.
+}
+
+func (e *Error) Error() string {
+	columns := make([]string, 0, len(e.Key))
+	for column := range e.Key {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	keys := make([]string, len(columns))
+	for i, column := range columns {
+		keys[i] = fmt.Sprintf("%s=%v", column, e.Key[column])
+	}
+
+	msg := fmt.Sprintf("%s(%s): %s", e.Table, strings.Join(keys, ","), e.Err)
+	if e.Cause != nil {
+		msg += ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Err, e.Cause}
+}
+
+// WrapError classifies err, returned by an operation on the row of table
+// identified by key, with the dialect. Errors the dialect does not recognize
+// are returned unchanged. A nil dialect is Postgres.
+func WrapError(dialect Dialect, table string, key map[string]interface{}, err error) error {
+	if err == nil {
+		return nil
+	}
+	if dialect == nil {
+		dialect = Postgres
+	}
+	if sentinel := dialect.Classify(err); sentinel != nil {
+		return &Error{Table: table, Key: key, Err: sentinel, Cause: err}
+	}
+	return err
+}
+
+// sqlState returns the SQLSTATE code of err, if the driver exposes one.
+func sqlState(err error) string {
+	var withState interface {
+		SQLState() string
+	}
+	if errors.As(err, &withState) {
+		return withState.SQLState()
+	}
+	return ""
+}
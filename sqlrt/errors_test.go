@@ -0,0 +1,60 @@
+package sqlrt
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+type stateError string
+
+func (e stateError) Error() string    { return "driver error" }
+func (e stateError) SQLState() string { return string(e) }
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		dialect  Dialect
+		err      error
+		expected error
+	}{
+		{Postgres, stateError("23505"), ErrDuplicate},
+		{Postgres, stateError("23503"), ErrForeignKey},
+		{Postgres, stateError("40001"), nil},
+		{Postgres, errors.New(`pq: duplicate key value violates unique constraint "foo_pkey"`), ErrDuplicate},
+		{MySQL, errors.New("Error 1062 (23000): Duplicate entry '1' for key 'PRIMARY'"), ErrDuplicate},
+		{MySQL, errors.New("Error 1452: Cannot add or update a child row"), ErrForeignKey},
+		{SQLite, errors.New("UNIQUE constraint failed: foo.id"), ErrDuplicate},
+		{SQLite, errors.New("FOREIGN KEY constraint failed"), ErrForeignKey},
+		{SQLite, errors.New("database is locked"), nil},
+	}
+
+	for _, c := range cases {
+		if actual := c.dialect.Classify(c.err); actual != c.expected {
+			t.Errorf("%s: Classify(%q) = %v, expected %v", c.dialect.Name(), c.err, actual, c.expected)
+		}
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	cause := stateError("23505")
+	err := WrapError(nil, "foo", map[string]interface{}{"id": 3}, cause)
+	if !errors.Is(err, ErrDuplicate) || !errors.Is(err, cause) {
+		t.Fatalf("Expected error wrapping ErrDuplicate and the cause, got %v", err)
+	}
+	if expected := "foo(id=3): sqlrt: duplicate key: driver error"; err.Error() != expected {
+		t.Fatalf("Mismatch in error string: expected %q, got %q", expected, err.Error())
+	}
+
+	other := errors.New("connection reset")
+	if err := WrapError(Postgres, "foo", nil, other); err != other {
+		t.Fatalf("Expected unrecognized error to be returned unchanged, got %v", err)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	errFooNotFound := NotFound("foo")
+	err := &Error{Table: "foo", Key: map[string]interface{}{"id": 3}, Err: errFooNotFound}
+	if !errors.Is(err, errFooNotFound) || !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("Expected error wrapping the sentinel and sql.ErrNoRows, got %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package sqlrt
+
+// Models implement these interfaces to run logic around persistence. An error
+// returned by a hook aborts the operation, and is returned unchanged: rolling
+// back the transaction it runs in is left to InTx, InSavepoint or the caller.
+type (
+	BeforeCreater interface {
+		BeforeCreate() error
+	}
+
+	BeforeUpdater interface {
+		BeforeUpdate() error
+	}
+
+	BeforeDeleter interface {
+		BeforeDelete() error
+	}
+
+	// AfterLoader is called on every object returned by a finder or query.
+	AfterLoader interface {
+		AfterLoad() error
+	}
+)
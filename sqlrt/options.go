@@ -0,0 +1,63 @@
+package sqlrt
+
+import (
+	"context"
+	"time"
+)
+
+// Logger is told about every statement run by generated code, before it runs.
+type Logger interface {
+	LogQuery(ctx context.Context, query string, args []interface{})
+}
+
+// LoggerFunc adapts a function to Logger.
+type LoggerFunc func(ctx context.Context, query string, args []interface{})
+
+func (f LoggerFunc) LogQuery(ctx context.Context, query string, args []interface{}) {
+	f(ctx, query, args)
+}
+
+// Log tells logger, if any, about query.
+func Log(ctx context.Context, logger Logger, query string, args []interface{}) {
+	if logger != nil {
+		logger.LogQuery(ctx, query, args)
+	}
+}
+
+// Options configure the queries returned by generated constructors.
+type Options struct {
+	Dialect Dialect
+	Logger  Logger
+	Warmup  bool             // Prepare statements on construction rather than on first use
+	Now     func() time.Time // Clock setting created, updated and deleted times
+}
+
+type Option func(*Options)
+
+// NewOptions returns the defaults, modified by opts: Postgres, no logging, and
+// statements prepared on first use.
+func NewOptions(opts ...Option) Options {
+	o := Options{Dialect: Postgres}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func WithDialect(dialect Dialect) Option {
+	return func(o *Options) { o.Dialect = dialect }
+}
+
+func WithLogger(logger Logger) Option {
+	return func(o *Options) { o.Logger = logger }
+}
+
+// WithWarmup prepares every statement on construction, checking it against
+// the schema, which then fails construction.
+func WithWarmup() Option {
+	return func(o *Options) { o.Warmup = true }
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(o *Options) { o.Now = now }
+}
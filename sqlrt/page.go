@@ -0,0 +1,154 @@
+package sqlrt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	ErrInvalidPageToken  = errors.New("sqlrt: invalid page token")
+	ErrInvalidSortColumn = errors.New("sqlrt: invalid sort column")
+)
+
+// Page selects one window of a finder's results. The zero Page returns every
+// matching row, ordered by primary key.
+type Page struct {
+	Size      int    // Maximum rows per page; 0 returns all rows.
+	SortBy    *Order // Sort column; the primary key if nil. Ties are broken by primary key.
+	UseOffset bool   // Page with OFFSET instead of comparing against the last row's keys.
+	Token     string // Next page token from the previous page; empty for the first page.
+}
+
+// pageToken is the decoded form of Page.Token.
+type pageToken struct {
+	Sort   string                     `json:"s"`
+	Desc   bool                       `json:"d,omitempty"`
+	Offset int                        `json:"o,omitempty"`
+	Keys   map[string]json.RawMessage `json:"k,omitempty"`
+}
+
+func (p Page) order(pk string) []Order {
+	if p.SortBy == nil || p.SortBy.Column == pk {
+		desc := p.SortBy != nil && p.SortBy.Desc
+		return []Order{{Column: pk, Desc: desc}}
+	}
+	return []Order{*p.SortBy, {Column: pk, Desc: p.SortBy.Desc}}
+}
+
+// decodeToken returns the decoded token, which must be of a page sorted by
+// sort, or nil for the first page.
+func (p Page) decodeToken(sort Order) (*pageToken, error) {
+	if p.Token == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(p.Token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	token := new(pageToken)
+	if err := json.Unmarshal(b, token); err != nil || token.Sort != sort.Column || token.Desc != sort.Desc {
+		return nil, ErrInvalidPageToken
+	}
+	return token, nil
+}
+
+// Apply adds ordering, limit and the position of the token to sel, replacing
+// any ordering already set. pk is the primary key column; key returns a
+// pointer to a value of the named column's type, to decode keys into, or nil
+// if there is no such column, which fails with ErrInvalidSortColumn.
+func (p Page) Apply(sel *Select, pk string, key func(column string) interface{}) error {
+	orders := p.order(pk)
+	// The sort column is written into the query as is.
+	if key(orders[0].Column) == nil {
+		return fmt.Errorf("%w: %q", ErrInvalidSortColumn, orders[0].Column)
+	}
+	token, err := p.decodeToken(orders[0])
+	if err != nil {
+		return err
+	}
+
+	sel.OrderBy = orders
+	sel.Limit = p.Size
+	if token == nil {
+		return nil
+	}
+
+	if p.UseOffset {
+		sel.Offset = token.Offset
+		return nil
+	}
+
+	values := make([]interface{}, len(orders))
+	for i, order := range orders {
+		ptr := key(order.Column)
+		raw, ok := token.Keys[order.Column]
+		if ptr == nil || !ok {
+			return ErrInvalidPageToken
+		}
+		if err := json.Unmarshal(raw, ptr); err != nil {
+			return ErrInvalidPageToken
+		}
+		values[i] = reflect.ValueOf(ptr).Elem().Interface()
+	}
+	sel.Where = append(sel.Where, after(orders, values))
+	return nil
+}
+
+// after matches rows sorting strictly after the row with the given keys.
+func after(orders []Order, values []interface{}) Predicate {
+	op := func(o Order) string {
+		if o.Desc {
+			return "<"
+		}
+		return ">"
+	}
+
+	if len(orders) == 1 {
+		return comparison{column: orders[0].Column, op: op(orders[0]), value: values[0]}
+	}
+	return Or(
+		comparison{column: orders[0].Column, op: op(orders[0]), value: values[0]},
+		And(
+			comparison{column: orders[0].Column, op: "=", value: values[0]},
+			comparison{column: orders[1].Column, op: op(orders[1]), value: values[1]},
+		),
+	)
+}
+
+// NextToken returns the token for the page after one that returned n rows, or
+// "" if there are no more rows. last returns a pointer to the named column of
+// the last row returned.
+func (p Page) NextToken(pk string, n int, last func(column string) interface{}) (string, error) {
+	if p.Size == 0 || n < p.Size {
+		return "", nil
+	}
+
+	orders := p.order(pk)
+	token := pageToken{Sort: orders[0].Column, Desc: orders[0].Desc}
+	if p.UseOffset {
+		if prev, err := p.decodeToken(orders[0]); err != nil {
+			return "", err
+		} else if prev != nil {
+			token.Offset = prev.Offset
+		}
+		token.Offset += n
+	} else {
+		token.Keys = make(map[string]json.RawMessage)
+		for _, order := range orders {
+			raw, err := json.Marshal(last(order.Column))
+			if err != nil {
+				return "", err
+			}
+			token.Keys[order.Column] = raw
+		}
+	}
+
+	b, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
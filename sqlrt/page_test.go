@@ -0,0 +1,116 @@
+package sqlrt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type row struct {
+	id      int64
+	created time.Time
+}
+
+func (r *row) ptr(column string) interface{} {
+	switch column {
+	case "id":
+		return &r.id
+	case "created":
+		return &r.created
+	}
+	return nil
+}
+
+func TestPageKeyset(t *testing.T) {
+	created := Column[time.Time]{Name: "created"}
+	order := created.Desc()
+	page := Page{Size: 2, SortBy: &order}
+
+	sel := Select{Table: "foo", Columns: []string{"id", "created"}}
+	if err := page.Apply(&sel, "id", new(row).ptr); err != nil {
+		t.Fatal(err)
+	}
+	if query, _ := sel.Build(Postgres); query != "SELECT id,created FROM foo ORDER BY created DESC,id DESC LIMIT $1" {
+		t.Fatalf("Unexpected first page query: %s", query)
+	}
+
+	last := &row{id: 1 << 60, created: time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC)}
+	token, err := page.NextToken("id", 2, last.ptr)
+	if err != nil {
+		t.Fatal(err)
+	} else if token == "" {
+		t.Fatal("Expected a next page token for a full page")
+	}
+
+	page.Token = token
+	sel = Select{Table: "foo", Columns: []string{"id", "created"}}
+	if err := page.Apply(&sel, "id", new(row).ptr); err != nil {
+		t.Fatal(err)
+	}
+	query, args := sel.Build(Postgres)
+	expectedQuery := "SELECT id,created FROM foo WHERE (created<$1) OR ((created=$2) AND (id<$3)) ORDER BY created DESC,id DESC LIMIT $4"
+	if query != expectedQuery {
+		t.Fatalf("Mismatch in query:\n-- %s\n++ %s\n", expectedQuery, query)
+	}
+	expectedArgs := []interface{}{last.created, last.created, last.id, 2}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Fatalf("Mismatch in args: expected %v, got %v\n", expectedArgs, args)
+	}
+
+	if token, err := page.NextToken("id", 1, last.ptr); err != nil || token != "" {
+		t.Fatalf("Expected no token after a partial page, got %q (%v)", token, err)
+	}
+}
+
+func TestPageOffset(t *testing.T) {
+	page := Page{Size: 10, UseOffset: true}
+	for _, expectedOffset := range []int{0, 10, 20} {
+		sel := Select{Table: "foo", Columns: []string{"id"}}
+		if err := page.Apply(&sel, "id", new(row).ptr); err != nil {
+			t.Fatal(err)
+		}
+		if sel.Offset != expectedOffset {
+			t.Fatalf("Expected offset %d, got %d", expectedOffset, sel.Offset)
+		}
+
+		token, err := page.NextToken("id", 10, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page.Token = token
+	}
+}
+
+func TestPageInvalidToken(t *testing.T) {
+	sel := Select{Table: "foo", Columns: []string{"id"}}
+	for _, token := range []string{"not base64!", "e30"} {
+		page := Page{Size: 10, Token: token}
+		if err := page.Apply(&sel, "id", new(row).ptr); err != ErrInvalidPageToken {
+			t.Fatalf("Expected ErrInvalidPageToken for %q, got %v", token, err)
+		}
+	}
+}
+
+func TestPageInvalidSort(t *testing.T) {
+	sel := Select{Table: "foo", Columns: []string{"id"}}
+	order := Order{Column: "id; DROP TABLE foo"}
+	page := Page{Size: 10, SortBy: &order}
+	if err := page.Apply(&sel, "id", new(row).ptr); !errors.Is(err, ErrInvalidSortColumn) {
+		t.Fatalf("Expected ErrInvalidSortColumn, got %v", err)
+	}
+
+	// A token is only valid for the direction of the sort it was issued for.
+	created := Column[time.Time]{Name: "created"}
+	order = created.Asc()
+	page = Page{Size: 2, SortBy: &order}
+	token, err := page.NextToken("id", 2, new(row).ptr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order = created.Desc()
+	page.Token = token
+	if err := page.Apply(&sel, "id", new(row).ptr); err != ErrInvalidPageToken {
+		t.Fatalf("Expected ErrInvalidPageToken for a token of the opposite direction, got %v", err)
+	}
+}
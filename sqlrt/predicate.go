@@ -0,0 +1,138 @@
+package sqlrt
+
+// Predicate is a boolean SQL expression used in a WHERE clause.
+type Predicate interface {
+	WriteSQL(b *Builder)
+}
+
+type comparison struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+func (c comparison) WriteSQL(b *Builder) {
+	b.WriteString(c.column).WriteString(c.op).Arg(c.value)
+}
+
+type nullCheck struct {
+	column string
+	isNull bool
+}
+
+func (n nullCheck) WriteSQL(b *Builder) {
+	b.WriteString(n.column)
+	if n.isNull {
+		b.WriteString(" IS NULL")
+	} else {
+		b.WriteString(" IS NOT NULL")
+	}
+}
+
+type inList struct {
+	column string
+	values []interface{}
+}
+
+func (in inList) WriteSQL(b *Builder) {
+	if len(in.values) == 0 {
+		// "x IN ()" is not valid SQL; an empty list matches nothing.
+		b.WriteString("1=0")
+		return
+	}
+	b.WriteString(in.column).WriteString(" IN (")
+	for i, v := range in.values {
+		if i != 0 {
+			b.WriteString(",")
+		}
+		b.Arg(v)
+	}
+	b.WriteString(")")
+}
+
+type junction struct {
+	op    string
+	preds []Predicate
+}
+
+func (j junction) WriteSQL(b *Builder) {
+	if len(j.preds) == 1 {
+		j.preds[0].WriteSQL(b)
+		return
+	}
+	for i, pred := range j.preds {
+		if i != 0 {
+			b.WriteString(j.op)
+		}
+		b.WriteString("(")
+		pred.WriteSQL(b)
+		b.WriteString(")")
+	}
+}
+
+// And matches rows satisfying all of preds.
+func And(preds ...Predicate) Predicate {
+	return junction{op: " AND ", preds: preds}
+}
+
+// Or matches rows satisfying any of preds.
+func Or(preds ...Predicate) Predicate {
+	return junction{op: " OR ", preds: preds}
+}
+
+type not struct {
+	pred Predicate
+}
+
+func (n not) WriteSQL(b *Builder) {
+	b.WriteString("NOT (")
+	n.pred.WriteSQL(b)
+	b.WriteString(")")
+}
+
+// Not matches rows not satisfying pred.
+func Not(pred Predicate) Predicate {
+	return not{pred: pred}
+}
+
+// Order is one term of an ORDER BY clause.
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+func (o Order) WriteSQL(b *Builder) {
+	b.WriteString(o.Column)
+	if o.Desc {
+		b.WriteString(" DESC")
+	} else {
+		b.WriteString(" ASC")
+	}
+}
+
+// Column is a typed reference to a table column. Generated code declares one
+// per field, so predicates only accept values of the field's source type.
+type Column[T any] struct {
+	Name string
+}
+
+func (c Column[T]) Eq(v T) Predicate { return comparison{column: c.Name, op: "=", value: v} }
+func (c Column[T]) Ne(v T) Predicate { return comparison{column: c.Name, op: "<>", value: v} }
+func (c Column[T]) Lt(v T) Predicate { return comparison{column: c.Name, op: "<", value: v} }
+func (c Column[T]) Le(v T) Predicate { return comparison{column: c.Name, op: "<=", value: v} }
+func (c Column[T]) Gt(v T) Predicate { return comparison{column: c.Name, op: ">", value: v} }
+func (c Column[T]) Ge(v T) Predicate { return comparison{column: c.Name, op: ">=", value: v} }
+
+func (c Column[T]) In(vs ...T) Predicate {
+	values := make([]interface{}, len(vs))
+	for i, v := range vs {
+		values[i] = v
+	}
+	return inList{column: c.Name, values: values}
+}
+
+func (c Column[T]) IsNull() Predicate    { return nullCheck{column: c.Name, isNull: true} }
+func (c Column[T]) IsNotNull() Predicate { return nullCheck{column: c.Name, isNull: false} }
+
+func (c Column[T]) Asc() Order  { return Order{Column: c.Name} }
+func (c Column[T]) Desc() Order { return Order{Column: c.Name, Desc: true} }
@@ -0,0 +1,54 @@
+package sqlrt
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// Savepoint sets the savepoint name in tx, which is a transaction or a
+// connection within one. Every dialect supports savepoints with the same
+// syntax. Names are identifiers, and are not quoted.
+func Savepoint(ctx context.Context, tx DBTX, name string) error {
+	_, err := tx.ExecContext(ctx, "SAVEPOINT "+name)
+	return err
+}
+
+// RollbackTo undoes the changes made since the savepoint name was set, which
+// remains set.
+func RollbackTo(ctx context.Context, tx DBTX, name string) error {
+	_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+	return err
+}
+
+// Release removes the savepoint name, keeping the changes made since.
+func Release(ctx context.Context, tx DBTX, name string) error {
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+var savepointSeq uint64
+
+// InSavepoint runs fn within a new savepoint of tx, which is released if fn
+// succeeds and rolled back to if it fails or panics, leaving the rest of the
+// transaction intact.
+func InSavepoint(ctx context.Context, tx DBTX, fn func() error) error {
+	name := fmt.Sprintf("sqlrt_%d", atomic.AddUint64(&savepointSeq, 1))
+	if err := Savepoint(ctx, tx, name); err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			RollbackTo(ctx, tx, name)
+			panic(p)
+		}
+	}()
+
+	if err := fn(); err != nil {
+		if RollbackTo(ctx, tx, name) == nil {
+			Release(ctx, tx, name)
+		}
+		return err
+	}
+	return Release(ctx, tx, name)
+}
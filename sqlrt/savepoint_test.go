@@ -0,0 +1,44 @@
+package sqlrt
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInSavepoint(t *testing.T) {
+	fnErr := errors.New("fn failed")
+	cases := []struct {
+		err      error
+		expected []string
+	}{
+		{nil, []string{"SAVEPOINT", "RELEASE SAVEPOINT"}},
+		{fnErr, []string{"SAVEPOINT", "ROLLBACK TO SAVEPOINT", "RELEASE SAVEPOINT"}},
+	}
+
+	for _, c := range cases {
+		db := new(recorder)
+		if err := InSavepoint(context.Background(), db, func() error { return c.err }); err != c.err {
+			t.Errorf("InSavepoint() = %v, expected %v", err, c.err)
+		}
+
+		// Every statement refers to the same savepoint.
+		var statements, names []string
+		for _, query := range db.queries {
+			i := strings.LastIndex(query, " ")
+			statements = append(statements, query[:i])
+			names = append(names, query[i+1:])
+		}
+		for _, name := range names {
+			if name != names[0] {
+				t.Errorf("Savepoints = %q, expected a single name", names)
+				break
+			}
+		}
+		if !reflect.DeepEqual(statements, c.expected) {
+			t.Errorf("Statements = %q, expected %q", statements, c.expected)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package sqlrt
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Stmt is a statement for a database, which runs on any DBTX. It is prepared
+// on the database on first use outside a transaction, and is safe for
+// concurrent use.
+type Stmt struct {
+	db     *sql.DB
+	logger Logger
+	query  string
+
+	mu   sync.Mutex
+	stmt *sql.Stmt // Nil until prepared
+}
+
+// NewStmt returns query for db, logged to logger if not nil.
+func NewStmt(db *sql.DB, logger Logger, query string) *Stmt {
+	return &Stmt{db: db, logger: logger, query: query}
+}
+
+// Prepare returns the statement prepared on the database, preparing it if
+// needed. A statement which failed to prepare is prepared again on next use.
+// The lock is not held while preparing, so concurrent first uses may both
+// prepare it, keeping the first.
+func (s *Stmt) Prepare(ctx context.Context) (*sql.Stmt, error) {
+	if stmt := s.prepared(); stmt != nil {
+		return stmt, nil
+	}
+	stmt, err := s.db.PrepareContext(ctx, s.query)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stmt != nil {
+		stmt.Close()
+		return s.stmt, nil
+	}
+	s.stmt = stmt
+	return stmt, nil
+}
+
+// prepared returns the prepared statement, or nil if it is not prepared.
+func (s *Stmt) prepared() *sql.Stmt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stmt
+}
+
+// Close releases the prepared statement. A closed Stmt is prepared again if
+// used.
+func (s *Stmt) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stmt == nil {
+		return nil
+	}
+	err := s.stmt.Close()
+	s.stmt = nil
+	return err
+}
+
+// PrepareAll prepares every statement, stopping at the first error.
+func PrepareAll(ctx context.Context, stmts ...*Stmt) error {
+	for _, stmt := range stmts {
+		if _, err := stmt.Prepare(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CloseAll closes every statement, returning the first error.
+func CloseAll(stmts ...*Stmt) error {
+	var first error
+	for _, stmt := range stmts {
+		if err := stmt.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// on returns the prepared statement to run on db, or nil if db cannot use it
+// and the query must be sent as is. Transactions are assumed to have been
+// started on the database the statement is prepared on, and only use it once
+// prepared: preparing it on the database would wait for a connection other
+// than that of the transaction. Failures to prepare are left to the query to
+// report.
+func (s *Stmt) on(ctx context.Context, db DBTX) *sql.Stmt {
+	switch db := db.(type) {
+	case *sql.DB:
+		if db == s.db {
+			stmt, _ := s.Prepare(ctx)
+			return stmt
+		}
+	case *sql.Tx:
+		if stmt := s.prepared(); stmt != nil {
+			return db.StmtContext(ctx, stmt)
+		}
+	}
+	return nil
+}
+
+func (s *Stmt) ExecContext(ctx context.Context, db DBTX, args ...interface{}) (sql.Result, error) {
+	Log(ctx, s.logger, s.query, args)
+	if stmt := s.on(ctx, db); stmt != nil {
+		return stmt.ExecContext(ctx, args...)
+	}
+	return db.ExecContext(ctx, s.query, args...)
+}
+
+func (s *Stmt) QueryRowContext(ctx context.Context, db DBTX, args ...interface{}) *sql.Row {
+	Log(ctx, s.logger, s.query, args)
+	if stmt := s.on(ctx, db); stmt != nil {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return db.QueryRowContext(ctx, s.query, args...)
+}
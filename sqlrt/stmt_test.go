@@ -0,0 +1,70 @@
+package sqlrt
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recorder is a DBTX which records the statements sent to it.
+type recorder struct {
+	DBTX
+	queries []string
+	args    [][]interface{}
+}
+
+func (r *recorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.queries = append(r.queries, query)
+	r.args = append(r.args, args)
+	return nil, nil
+}
+
+func TestStmtUnpreparedExecutor(t *testing.T) {
+	stmt := &Stmt{query: "DELETE FROM foo WHERE id=$1"}
+	db := new(recorder)
+	if _, err := stmt.ExecContext(context.Background(), db, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"DELETE FROM foo WHERE id=$1"}; !reflect.DeepEqual(db.queries, expected) {
+		t.Errorf("Queries = %q, expected %q", db.queries, expected)
+	}
+	if expected := [][]interface{}{{3}}; !reflect.DeepEqual(db.args, expected) {
+		t.Errorf("Args = %v, expected %v", db.args, expected)
+	}
+}
+
+func TestCloseUnprepared(t *testing.T) {
+	if err := CloseAll(NewStmt(nil, nil, "SELECT 1"), NewStmt(nil, nil, "SELECT 2")); err != nil {
+		t.Errorf("CloseAll() = %v, expected nil", err)
+	}
+}
+
+func TestStmtUnpreparedTx(t *testing.T) {
+	// The only connection is held by the transaction, so the statement cannot
+	// be prepared on the database.
+	r := new(txRecorder)
+	db := sql.OpenDB(r)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := NewStmt(db, nil, "DELETE FROM foo WHERE id=$1")
+	if _, err := stmt.ExecContext(ctx, tx, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"BEGIN", "DELETE FROM foo WHERE id=$1", "COMMIT"}; !reflect.DeepEqual(r.events, expected) {
+		t.Errorf("Events = %q, expected %q", r.events, expected)
+	}
+}
@@ -0,0 +1,105 @@
+package sqlrt
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Backoff returns the delay before the given retry (1-based) of a transaction.
+type Backoff func(retry int) time.Duration
+
+// ExponentialBackoff doubles the delay from base on every retry, up to max.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(retry int) time.Duration {
+		delay := base
+		for i := 1; i < retry && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
+
+// TxOptions configures InTx. The zero value runs transactions with the
+// driver's default isolation level, making up to 3 attempts.
+type TxOptions struct {
+	sql.TxOptions
+
+	MaxAttempts int     // Defaults to 3
+	Backoff     Backoff // Defaults to ExponentialBackoff(10ms, 1s)
+}
+
+func (o *TxOptions) maxAttempts() int {
+	if o == nil || o.MaxAttempts == 0 {
+		return 3
+	}
+	return o.MaxAttempts
+}
+
+func (o *TxOptions) backoff() Backoff {
+	if o == nil || o.Backoff == nil {
+		return ExponentialBackoff(10*time.Millisecond, time.Second)
+	}
+	return o.Backoff
+}
+
+func (o *TxOptions) txOptions() *sql.TxOptions {
+	if o == nil {
+		return nil
+	}
+	return &o.TxOptions
+}
+
+// InTx runs fn in a transaction on db, which is committed if fn succeeds and
+// rolled back if it fails or panics. Transactions aborted by a serialization
+// failure or deadlock, as reported by the dialect, are run again after a
+// backoff. A nil dialect is Postgres, and nil opts use the defaults.
+func InTx(ctx context.Context, db *sql.DB, dialect Dialect, opts *TxOptions, fn func(tx *sql.Tx) error) error {
+	if dialect == nil {
+		dialect = Postgres
+	}
+	return retry(ctx, dialect, opts, func() error {
+		return runTx(ctx, db, opts.txOptions(), fn)
+	})
+}
+
+// retry calls attempt until it succeeds, fails with an error which is not
+// retryable, or runs out of attempts.
+func retry(ctx context.Context, dialect Dialect, opts *TxOptions, attempt func() error) error {
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil || n >= opts.maxAttempts() || !dialect.Retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(opts.backoff()(n))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func runTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,144 @@
+package sqlrt
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	cases := []struct {
+		dialect  Dialect
+		err      error
+		expected bool
+	}{
+		{Postgres, stateError("40001"), true},
+		{Postgres, stateError("40P01"), true},
+		{Postgres, stateError("23505"), false},
+		{Postgres, errors.New("pq: could not serialize access due to concurrent update"), true},
+		{MySQL, errors.New("Error 1213 (40001): Deadlock found when trying to get lock"), true},
+		{MySQL, errors.New("Error 1062 (23000): Duplicate entry '1' for key 'PRIMARY'"), false},
+		{SQLite, errors.New("database is locked"), true},
+		{SQLite, errors.New("UNIQUE constraint failed: foo.id"), false},
+	}
+
+	for _, c := range cases {
+		if actual := c.dialect.Retryable(c.err); actual != c.expected {
+			t.Errorf("%s: Retryable(%q) = %v, expected %v", c.dialect.Name(), c.err, actual, c.expected)
+		}
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}
+	for i, delay := range expected {
+		if actual := backoff(i + 1); actual != delay {
+			t.Errorf("backoff(%d) = %s, expected %s", i+1, actual, delay)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	opts := &TxOptions{MaxAttempts: 3, Backoff: func(int) time.Duration { return 0 }}
+	cases := []struct {
+		errs     []error
+		expected error
+		attempts int
+	}{
+		{[]error{nil}, nil, 1},
+		{[]error{stateError("40001"), nil}, nil, 2},
+		{[]error{stateError("40001"), stateError("40P01"), stateError("40001")}, stateError("40001"), 3},
+		{[]error{stateError("23505")}, stateError("23505"), 1},
+	}
+
+	for _, c := range cases {
+		attempts := 0
+		err := retry(context.Background(), Postgres, opts, func() error {
+			attempts++
+			return c.errs[attempts-1]
+		})
+		if err != c.expected || attempts != c.attempts {
+			t.Errorf("retry(%v) = %v after %d attempts, expected %v after %d", c.errs, err, attempts, c.expected, c.attempts)
+		}
+	}
+}
+
+// txRecorder is a database/sql driver connection recording the transactions
+// begun, committed and rolled back on it, and the statements executed.
+type txRecorder struct {
+	events []string
+}
+
+func (r *txRecorder) Connect(context.Context) (driver.Conn, error) { return r, nil }
+func (r *txRecorder) Driver() driver.Driver                        { return nil }
+
+func (r *txRecorder) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (r *txRecorder) Close() error { return nil }
+
+func (r *txRecorder) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	r.events = append(r.events, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (r *txRecorder) Begin() (driver.Tx, error) {
+	r.events = append(r.events, "BEGIN")
+	return r, nil
+}
+
+func (r *txRecorder) Commit() error {
+	r.events = append(r.events, "COMMIT")
+	return nil
+}
+
+func (r *txRecorder) Rollback() error {
+	r.events = append(r.events, "ROLLBACK")
+	return nil
+}
+
+func TestRunTx(t *testing.T) {
+	fnErr := errors.New("fn failed")
+	cases := []struct {
+		err      error
+		expected []string
+	}{
+		{nil, []string{"BEGIN", "COMMIT"}},
+		{fnErr, []string{"BEGIN", "ROLLBACK"}},
+	}
+
+	for _, c := range cases {
+		r := new(txRecorder)
+		db := sql.OpenDB(r)
+		err := runTx(context.Background(), db, nil, func(tx *sql.Tx) error { return c.err })
+		if err != c.err {
+			t.Errorf("runTx() = %v, expected %v", err, c.err)
+		}
+		if !reflect.DeepEqual(r.events, c.expected) {
+			t.Errorf("Events = %q, expected %q", r.events, c.expected)
+		}
+		db.Close()
+	}
+}
+
+func TestRunTxPanic(t *testing.T) {
+	r := new(txRecorder)
+	db := sql.OpenDB(r)
+	defer db.Close()
+
+	defer func() {
+		if p := recover(); p != "fn panicked" {
+			t.Errorf("Recovered %v, expected the panic of fn", p)
+		}
+		if expected := []string{"BEGIN", "ROLLBACK"}; !reflect.DeepEqual(r.events, expected) {
+			t.Errorf("Events = %q, expected %q", r.events, expected)
+		}
+	}()
+	runTx(context.Background(), db, nil, func(tx *sql.Tx) error { panic("fn panicked") })
+	t.Error("Expected runTx to panic again")
+}